@@ -0,0 +1,113 @@
+package bigq
+
+import (
+	"github.com/pacer/go-bigq/internal/bridge"
+)
+
+// AnalyzeOutput describes a successfully analyzed statement.
+type AnalyzeOutput struct {
+	// StatementKind is the resolved statement kind, e.g. "QueryStmt",
+	// "InsertStmt" or "CreateTableStmt".
+	StatementKind string
+
+	// OutputColumns are the columns a query produces, in order.
+	OutputColumns []ColumnDef
+
+	// Tables are the full names of the tables the statement reads or writes.
+	Tables []string
+
+	// Columns are the table columns the statement actually references.
+	Columns []ColumnRef
+
+	// Warnings are non-fatal analyzer findings, such as deprecated syntax.
+	Warnings []string
+
+	// Resolved is the root of the resolved AST. Node kinds are resolved
+	// node kinds ("QueryStmt", "ProjectScan", "ColumnRef", ...); Start and
+	// End are -1 when the analyzer recorded no location. Table scans carry
+	// the table name and column references the column name in Name.
+	Resolved *Node
+
+	// NamePath is the name of the object a CREATE, DROP or ALTER statement
+	// acts on, e.g. ["dataset", "table"], or of the procedure a CALL
+	// statement calls.
+	NamePath []string
+
+	// ColumnDefinitions are the columns of the table or view a CREATE
+	// statement defines, including CREATE TABLE ... AS SELECT.
+	ColumnDefinitions []ColumnDef
+
+	// ObjectType is the kind of object a DROP statement drops, e.g. "TABLE"
+	// or "VIEW".
+	ObjectType string
+
+	// AlterActions are the column changes of an ALTER TABLE statement.
+	AlterActions []AlterAction
+
+	// Arguments and Body are the arguments and body of a CREATE PROCEDURE
+	// statement.
+	Arguments []Argument
+	Body      string
+
+	// UndeclaredParameters are the query parameters the statement uses
+	// without their being declared, with the types inferred from their
+	// use, if the catalog was created WithUndeclaredParameters. Named
+	// parameters come first, by name, then positional ones in order.
+	UndeclaredParameters []Parameter
+}
+
+// Argument is an argument of a procedure.
+type Argument struct {
+	Name     string
+	TypeName string // "ANY TYPE" for a templated argument
+	Mode     string // "IN", "OUT" or "INOUT"
+}
+
+// AlterAction is one action of an ALTER TABLE statement.
+type AlterAction struct {
+	Kind     string // "AddColumnAction", "DropColumnAction", "RenameColumnAction" or "AlterColumnSetDataTypeAction"
+	Column   string
+	NewName  string // RENAME COLUMN only
+	TypeName string // ADD COLUMN and SET DATA TYPE only
+	Mode     string // "REQUIRED" for an added NOT NULL column
+	IfExists bool   // ADD COLUMN IF NOT EXISTS or DROP COLUMN IF EXISTS
+}
+
+// ColumnRef identifies a column of a table referenced by a statement.
+type ColumnRef struct {
+	Table  string
+	Column string
+}
+
+func newAnalyzeOutput(out *bridge.AnalyzeOutput) *AnalyzeOutput {
+	a := &AnalyzeOutput{
+		StatementKind: out.StatementKind,
+		Tables:        out.Tables,
+		Warnings:      out.Warnings,
+		Resolved:      buildTree(out.Resolved),
+		NamePath:      out.NamePath,
+		ObjectType:    out.ObjectType,
+		Body:          out.Body,
+	}
+	a.OutputColumns = fromBridgeColumns(out.OutputColumns)
+	a.ColumnDefinitions = fromBridgeColumns(out.ColumnDefinitions)
+	a.UndeclaredParameters = fromBridgeParameters(out.UndeclaredParameters)
+	for _, c := range out.AlterActions {
+		a.AlterActions = append(a.AlterActions, AlterAction(c))
+	}
+	for _, arg := range out.Arguments {
+		a.Arguments = append(a.Arguments, Argument(arg))
+	}
+	for _, c := range out.Columns {
+		a.Columns = append(a.Columns, ColumnRef{Table: c.Table, Column: c.Column})
+	}
+	return a
+}
+
+func fromBridgeColumns(columns []bridge.NameAndType) []ColumnDef {
+	var out []ColumnDef
+	for _, c := range columns {
+		out = append(out, ColumnDef{Name: c.Name, TypeName: c.TypeName, Mode: c.Mode})
+	}
+	return out
+}
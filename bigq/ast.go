@@ -0,0 +1,146 @@
+package bigq
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pacer/go-bigq/internal/bridge"
+)
+
+// Node is a node of a ZetaSQL parse tree.
+type Node struct {
+	Kind     string // node kind, e.g. "QueryStatement", "PathExpression", "IfStatement"
+	Name     string // identifier value or literal image, empty for other nodes
+	Start    int    // byte offset of the first character in the parsed SQL
+	End      int    // byte offset one past the last character
+	Parent   *Node
+	Children []*Node
+	flags    int
+}
+
+// IsStatement reports whether n is a statement, SQL or scripting.
+func (n *Node) IsStatement() bool { return n.flags&bridge.ASTStatement != 0 }
+
+// IsScriptStatement reports whether n is a scripting statement such as
+// DECLARE, SET, IF, LOOP, WHILE or BEGIN...END.
+func (n *Node) IsScriptStatement() bool { return n.flags&bridge.ASTScriptStatement != 0 }
+
+// IsSQLStatement reports whether n is a statement the analyzer can resolve
+// on its own: a query, DML, DDL or other non-scripting statement.
+func (n *Node) IsSQLStatement() bool { return n.IsStatement() && !n.IsScriptStatement() }
+
+// IsExpression reports whether n is a scalar expression.
+func (n *Node) IsExpression() bool { return n.flags&bridge.ASTExpression != 0 }
+
+// IsQueryExpression reports whether n is a query expression (SELECT, set
+// operation or parenthesized query).
+func (n *Node) IsQueryExpression() bool { return n.flags&bridge.ASTQueryExpression != 0 }
+
+// IsTableExpression reports whether n is a FROM clause item.
+func (n *Node) IsTableExpression() bool { return n.flags&bridge.ASTTableExpression != 0 }
+
+// IsType reports whether n is a type, e.g. in DECLARE or CAST.
+func (n *Node) IsType() bool { return n.flags&bridge.ASTType != 0 }
+
+// Text returns the source text of n. sql must be the string that was parsed.
+func (n *Node) Text(sql string) string {
+	return sql[n.Start:n.End]
+}
+
+// Position converts a byte offset in sql, such as a node's Start, to a
+// 1-based line and column. Columns count characters, not bytes.
+func Position(sql string, offset int) (line, column int) {
+	offset = min(max(offset, 0), len(sql))
+	lineStart := strings.LastIndexByte(sql[:offset], '\n') + 1
+	line = strings.Count(sql[:lineStart], "\n") + 1
+	column = utf8.RuneCountInString(sql[lineStart:offset]) + 1
+	return line, column
+}
+
+// Path returns the identifier names of a PathExpression node, e.g.
+// ["project", "dataset", "table"]. For other nodes it returns nil.
+func (n *Node) Path() []string {
+	if n.Kind != "PathExpression" {
+		return nil
+	}
+	var path []string
+	for _, c := range n.Children {
+		if c.Kind == "Identifier" {
+			path = append(path, c.Name)
+		}
+	}
+	return path
+}
+
+// Walk calls fn for n and each of its descendants in depth-first order.
+// If fn returns false, the children of that node are skipped.
+func (n *Node) Walk(fn func(*Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, c := range n.Children {
+		c.Walk(fn)
+	}
+}
+
+// Find returns all descendants of n (including n) with the given kind.
+func (n *Node) Find(kind string) []*Node {
+	var found []*Node
+	n.Walk(func(c *Node) bool {
+		if c.Kind == kind {
+			found = append(found, c)
+		}
+		return true
+	})
+	return found
+}
+
+// Script is the parse tree of a SQL script.
+type Script struct {
+	SQL  string
+	Root *Node // the "Script" node
+}
+
+// Statements returns the top-level statements of the script.
+func (s *Script) Statements() []*Node {
+	for _, c := range s.Root.Children {
+		if c.Kind == "StatementList" {
+			return c.Children
+		}
+	}
+	return nil
+}
+
+// ParseScriptAST parses a SQL script like ParseScript and returns its parse
+// tree, so callers can inspect statements, expressions, table paths and
+// scripting blocks.
+func ParseScriptAST(sql string) (*Script, error) {
+	nodes, err := bridge.ParseScriptAST(sql)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return &Script{SQL: sql, Root: buildTree(nodes)}, nil
+}
+
+// buildTree links a pre-order node list into a tree and returns its root.
+func buildTree(nodes []bridge.ASTNode) *Node {
+	if len(nodes) == 0 {
+		return nil
+	}
+	tree := make([]*Node, len(nodes))
+	for i, n := range nodes {
+		tree[i] = &Node{
+			Kind:  n.Kind,
+			Name:  n.Name,
+			Start: n.Start,
+			End:   n.End,
+			flags: n.Flags,
+		}
+		if n.Parent >= 0 {
+			parent := tree[n.Parent]
+			tree[i].Parent = parent
+			parent.Children = append(parent.Children, tree[i])
+		}
+	}
+	return tree[0]
+}
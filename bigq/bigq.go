@@ -3,25 +3,51 @@
 package bigq
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+
 	"github.com/pacer/go-bigq/internal/bridge"
 )
 
-// ParseStatement parses a single SQL statement and returns a syntax error if any.
+// ParseStatement parses a single SQL statement and returns a syntax error if
+// any, as an *Error.
 func ParseStatement(sql string) error {
-	return bridge.ParseStatement(sql)
+	return newError(bridge.ParseStatement(sql))
 }
 
 // ParseScript parses a SQL script (potentially multi-statement, including
 // scripting constructs like DECLARE, SET, IF/END IF, ASSERT, etc.) and
-// returns a syntax error if any. This is a superset of ParseStatement.
+// returns a syntax error if any, as an *Error. This is a superset of
+// ParseStatement.
 func ParseScript(sql string) error {
-	return bridge.ParseScript(sql)
+	return newError(bridge.ParseScript(sql))
+}
+
+// Keywords returns the GoogleSQL keywords, reserved and not, in upper case.
+func Keywords() []string {
+	return bridge.Keywords()
 }
 
 // AnalyzeStatement analyzes a SQL statement against a catalog, returning
 // an error if the SQL references unknown tables, columns, or functions.
-func AnalyzeStatement(sql string, catalog *Catalog) error {
-	return bridge.AnalyzeStatement(sql, catalog.inner, catalog.opts)
+// On success it describes what the statement resolved to. Syntax and
+// analysis errors are returned as an *Error.
+//
+// A wildcard table such as `dataset.events_*` resolves to the tables of
+// the dataset whose names start with events_. Its columns are the union
+// of theirs, plus the _TABLE_SUFFIX pseudo-column. INFORMATION_SCHEMA
+// views resolve if the catalog was created WithInformationSchema.
+//
+// Query parameters must be declared with AddParameter or
+// AddPositionalParameter, unless the catalog was created
+// WithUndeclaredParameters.
+func AnalyzeStatement(sql string, catalog *Catalog) (*AnalyzeOutput, error) {
+	out, err := catalog.analyze(sql, catalog.catalog(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return newAnalyzeOutput(out), nil
 }
 
 // Catalog holds schema information (tables, functions) used during SQL analysis.
@@ -30,13 +56,60 @@ type Catalog struct {
 	factory  *bridge.TypeFactory
 	langOpts *bridge.LanguageOptions
 	opts     *bridge.AnalyzerOptions
+
+	// lookup resolves names in inner first and then under the default
+	// dataset and project. It is nil when no defaults are set.
+	lookup *bridge.MultiCatalog
+
+	// defaults are the dotted prefixes that unqualified names are tried
+	// under, most specific first: the default dataset, then the project.
+	defaults []string
+
+	// defaultProject and defaultDataset are the defaults as configured.
+	defaultProject string
+	defaultDataset string
+
+	// tables mirrors the tables added with AddTable, keyed by lower-cased
+	// name, so their columns can be inspected from Go. tableNames holds
+	// their names as added.
+	tables     map[string][]ColumnDef
+	tableNames []string
+
+	// routines are the functions added with AddRoutine. The catalog refers
+	// to them without owning them, so they are released after it.
+	routines []*bridge.Routine
+
+	// procedures are the procedures added with AddRoutine, keyed by
+	// lower-cased name, so CALL statements can be checked from Go.
+	procedures map[string]*Procedure
+
+	// functions are the names of the functions and table functions added
+	// with AddRoutine.
+	functions []string
+
+	// views are the INFORMATION_SCHEMA views, keyed by upper-cased name,
+	// if enabled. Their tables are built once, owned by viewCatalog, and
+	// aliased under the names statements query them by.
+	views       map[string]informationSchemaTable
+	viewCatalog *bridge.SimpleCatalog
+
+	// namedParameters and positionalParameters count the declared query
+	// parameters; undeclaredParameters allows others.
+	namedParameters      int
+	positionalParameters int
+	undeclaredParameters bool
 }
 
 // CatalogOption configures catalog creation.
 type CatalogOption func(*catalogConfig)
 
 type catalogConfig struct {
-	productMode int
+	productMode       int
+	defaultProject    string
+	defaultDataset    string
+	informationSchema bool
+
+	undeclaredParameters bool
 }
 
 // WithProductMode sets the SQL product mode.
@@ -47,6 +120,53 @@ func WithProductMode(mode int) CatalogOption {
 	}
 }
 
+// WithDefaultProject sets the project that dataset-qualified table names
+// (dataset.table) belong to when they don't name one, as a BigQuery job's
+// project does.
+func WithDefaultProject(project string) CatalogOption {
+	return func(c *catalogConfig) {
+		c.defaultProject = project
+	}
+}
+
+// WithDefaultDataset sets the dataset that unqualified table names belong
+// to, as a BigQuery job's default dataset does. The dataset may be given as
+// "dataset", in which case it belongs to the default project if one is
+// set, or as "project.dataset".
+func WithDefaultDataset(dataset string) CatalogOption {
+	return func(c *catalogConfig) {
+		c.defaultDataset = dataset
+	}
+}
+
+// WithInformationSchema makes BigQuery's documented INFORMATION_SCHEMA
+// views available, at the dataset level (dataset.INFORMATION_SCHEMA.TABLES)
+// and at the region level (`region-us`.INFORMATION_SCHEMA.JOBS), for any
+// dataset or region and optionally qualified by a project. The dataset may
+// be left out only if a default dataset is set.
+func WithInformationSchema() CatalogOption {
+	return func(c *catalogConfig) {
+		c.informationSchema = true
+	}
+}
+
+// defaultPaths returns the catalog paths that names are resolved under
+// when they aren't found as written, most specific first.
+func (c *catalogConfig) defaultPaths() [][]string {
+	var paths [][]string
+	if c.defaultDataset != "" {
+		path := strings.Split(c.defaultDataset, ".")
+		if len(path) == 1 && c.defaultProject != "" {
+			path = []string{c.defaultProject, c.defaultDataset}
+		}
+		paths = append(paths, path)
+	}
+	if c.defaultProject != "" {
+		paths = append(paths, []string{c.defaultProject})
+	}
+	return paths
+}
+
 // NewCatalog creates a new catalog with builtin BigQuery functions and types.
 func NewCatalog(name string, options ...CatalogOption) (*Catalog, error) {
 	cfg := &catalogConfig{
@@ -67,35 +187,198 @@ func NewCatalog(name string, options ...CatalogOption) (*Catalog, error) {
 		return nil, err
 	}
 
+	// Names that aren't found as written are looked up again inside the
+	// default dataset and project sub-catalogs, the way BigQuery qualifies
+	// them. The sub-catalogs are created now and filled as tables are added.
+	var lookup *bridge.MultiCatalog
+	var defaults []string
+	if paths := cfg.defaultPaths(); len(paths) > 0 {
+		catalogs := []bridge.Catalog{catalog}
+		for _, path := range paths {
+			sub := catalog
+			for _, part := range path {
+				sub = sub.AddSubCatalog(part)
+			}
+			catalogs = append(catalogs, sub)
+			defaults = append(defaults, strings.Join(path, "."))
+		}
+		var err error
+		if lookup, err = bridge.NewMultiCatalog(name, catalogs...); err != nil {
+			catalog.Close()
+			return nil, err
+		}
+	}
+
 	analyzerOpts := bridge.NewAnalyzerOptions()
 	analyzerOpts.SetLanguageOptions(langOpts)
+	analyzerOpts.SetAllowUndeclaredParameters(cfg.undeclaredParameters)
+	c := &Catalog{
+		inner:          catalog,
+		factory:        factory,
+		langOpts:       langOpts,
+		opts:           analyzerOpts,
+		lookup:         lookup,
+		defaults:       defaults,
+		defaultProject: cfg.defaultProject,
+		defaultDataset: cfg.defaultDataset,
+		tables:         make(map[string][]ColumnDef),
+		procedures:     make(map[string]*Procedure),
+
+		undeclaredParameters: cfg.undeclaredParameters,
+	}
+	err := addSystemVariables(analyzerOpts, catalog)
+	if err == nil && cfg.informationSchema {
+		err = c.loadInformationSchema()
+	}
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
 
-	return &Catalog{
-		inner:    catalog,
-		factory:  factory,
-		langOpts: langOpts,
-		opts:     analyzerOpts,
-	}, nil
+// catalog returns the catalog statements are analyzed against.
+func (c *Catalog) catalog() bridge.Catalog {
+	if c.lookup != nil {
+		return c.lookup
+	}
+	return c.inner
 }
 
 // AddTable adds a table to the catalog.
-// The table name can be qualified (e.g. "project.dataset.table") - intermediate
-// sub-catalogs will be created automatically.
+// The table name can be qualified (e.g. "project.dataset.table"), in which
+// case it is nested in project and dataset sub-catalogs created as needed.
+// It resolves however BigQuery lets the path be written: as
+// project.dataset.table, `project.dataset.table`, `project`.dataset.table,
+// `project.dataset`.table and so on.
 func (c *Catalog) AddTable(name string, columns []ColumnDef) error {
-	return c.inner.AddTable(name, toBridgeColumns(columns))
+	key := strings.ToLower(name)
+	if _, ok := c.tables[key]; ok {
+		return fmt.Errorf("table %s already exists", name)
+	}
+	columns, err := normalizeColumns(columns)
+	if err != nil {
+		return fmt.Errorf("table %s: %w", name, err)
+	}
+	if err := addTable(c.inner, c.opts, name, columns); err != nil {
+		return err
+	}
+	c.tables[key] = columns
+	c.tableNames = append(c.tableNames, name)
+	return nil
+}
+
+// addTable registers a table with cat under every path it can be written
+// as (see registerPaths). The fully nested form owns the table and the
+// others are aliases of it.
+func addTable(cat *bridge.SimpleCatalog, opts *bridge.AnalyzerOptions, name string, columns []ColumnDef) error {
+	table, err := bridge.NewTable(name, toBridgeColumns(columns), cat, opts)
+	if err != nil {
+		return err
+	}
+	return registerPaths(cat, name, func(sub *bridge.SimpleCatalog, leaf string, first bool) error {
+		if first {
+			sub.AddTable(leaf, table)
+		} else {
+			sub.AddTableAlias(leaf, table)
+		}
+		return nil
+	})
+}
+
+// registerPaths calls add once for every way a dotted name can be split
+// into path components, with the sub-catalog that holds the last component
+// and that component's name. The first call is for the fully nested form.
+func registerPaths(cat *bridge.SimpleCatalog, name string, add func(sub *bridge.SimpleCatalog, leaf string, first bool) error) error {
+	for i, path := range splitPaths(strings.Split(name, ".")) {
+		sub := cat
+		for _, part := range path[:len(path)-1] {
+			sub = sub.AddSubCatalog(part)
+		}
+		if err := add(sub, path[len(path)-1], i == 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitPaths returns every way of grouping parts into consecutive
+// dot-joined path components, starting with parts itself. For
+// ["p", "d", "t"] that is [p d t], [p d.t], [p.d t] and [p.d.t].
+func splitPaths(parts []string) [][]string {
+	var paths [][]string
+	for i := 1; i < len(parts); i++ {
+		head := strings.Join(parts[:i], ".")
+		for _, rest := range splitPaths(parts[i:]) {
+			paths = append(paths, append([]string{head}, rest...))
+		}
+	}
+	return append(paths, []string{strings.Join(parts, ".")})
+}
+
+// Table returns the columns of a table added with AddTable. Names are
+// case-insensitive and resolved against the default dataset and project
+// like names in SQL.
+func (c *Catalog) Table(name string) ([]ColumnDef, bool) {
+	key, ok := c.resolveTable(name)
+	if !ok {
+		return nil, false
+	}
+	return c.tables[key], true
+}
+
+// TableNames returns the names of the tables added with AddTable, as they
+// were added.
+func (c *Catalog) TableNames() []string {
+	return slices.Clone(c.tableNames)
+}
+
+// resolveTable returns the lower-cased full name of the table that name
+// refers to.
+func (c *Catalog) resolveTable(name string) (string, bool) {
+	return c.resolve(name, func(key string) bool {
+		_, ok := c.tables[key]
+		return ok
+	})
+}
+
+// resolve returns the lower-cased full name that name refers to: name
+// itself or name under a default prefix, whichever exists first.
+func (c *Catalog) resolve(name string, exists func(key string) bool) (string, bool) {
+	key := strings.ToLower(name)
+	if exists(key) {
+		return key, true
+	}
+	for _, prefix := range c.defaults {
+		qualified := strings.ToLower(prefix) + "." + key
+		if exists(qualified) {
+			return qualified, true
+		}
+	}
+	return "", false
 }
 
-// AddSubCatalog adds a named sub-catalog (e.g. for a dataset).
+// AddSubCatalog returns the named sub-catalog (e.g. for a project or
+// dataset), creating it if needed.
 func (c *Catalog) AddSubCatalog(name string) *SubCatalog {
-	sub := c.inner.AddSubCatalog(name)
-	return &SubCatalog{inner: sub}
+	c.inner.AddSubCatalog(name)
+	return &SubCatalog{root: c, path: []string{name}}
 }
 
 // Close releases all resources held by the catalog.
 func (c *Catalog) Close() {
+	if c.lookup != nil {
+		c.lookup.Close()
+	}
 	if c.inner != nil {
 		c.inner.Close()
 	}
+	for _, r := range c.routines {
+		r.Close()
+	}
+	if c.viewCatalog != nil {
+		c.viewCatalog.Close()
+	}
 	if c.opts != nil {
 		c.opts.Close()
 	}
@@ -107,26 +390,90 @@ func (c *Catalog) Close() {
 	}
 }
 
-// SubCatalog represents a nested catalog (e.g. a dataset).
+// SubCatalog represents a nested catalog (e.g. a project or dataset).
 type SubCatalog struct {
-	inner *bridge.SimpleCatalog
+	root *Catalog
+	path []string
 }
 
-// AddTable adds a table to this sub-catalog.
+// AddTable adds a table to this sub-catalog. It is equivalent to adding
+// the table to the root catalog under its qualified name, so the same
+// BigQuery path forms resolve to it.
 func (s *SubCatalog) AddTable(name string, columns []ColumnDef) error {
-	return s.inner.AddTable(name, toBridgeColumns(columns))
+	return s.root.AddTable(strings.Join(s.qualify(name), "."), columns)
+}
+
+// AddSubCatalog returns the named sub-catalog of this one, creating it if
+// needed.
+func (s *SubCatalog) AddSubCatalog(name string) *SubCatalog {
+	path := s.qualify(name)
+	cat := s.root.inner
+	for _, part := range path {
+		cat = cat.AddSubCatalog(part)
+	}
+	return &SubCatalog{root: s.root, path: path}
+}
+
+func (s *SubCatalog) qualify(name string) []string {
+	return append(slices.Clone(s.path), name)
 }
 
 // ColumnDef defines a table column.
 type ColumnDef struct {
 	Name     string
-	TypeName string // BigQuery type: INT64, STRING, ARRAY<STRING>, STRUCT<a INT64, b STRING>, etc.
+	TypeName string // BigQuery type: INT64, STRING(50), ARRAY<STRING>, STRUCT<a INT64, b STRING>, etc.
+
+	// Mode is the BigQuery column mode: "NULLABLE" (or empty), "REQUIRED"
+	// or "REPEATED", in any case. As in BigQuery's schemas, a REPEATED
+	// column may be given its element type rather than an ARRAY type.
+	// REQUIRED is checked by the linter against INSERT, UPDATE and MERGE.
+	// Tables report their columns' modes as "", "REQUIRED" or "REPEATED".
+	Mode string
+
+	// Pseudo marks a pseudo-column such as _PARTITIONTIME or _TABLE_SUFFIX,
+	// which can be selected by name but is not part of SELECT *.
+	Pseudo bool
+}
+
+// Required reports whether the column is REQUIRED (NOT NULL).
+func (c ColumnDef) Required() bool {
+	return strings.EqualFold(c.Mode, "REQUIRED")
+}
+
+// normalizeColumns returns columns with their modes spelled "" (for
+// NULLABLE), "REQUIRED" or "REPEATED", and the types of REPEATED columns
+// given their element type wrapped in ARRAY<>.
+func normalizeColumns(columns []ColumnDef) ([]ColumnDef, error) {
+	out := slices.Clone(columns)
+	for i := range out {
+		c := &out[i]
+		switch mode := strings.ToUpper(c.Mode); mode {
+		case "", "NULLABLE":
+			c.Mode = ""
+		case "REQUIRED":
+			c.Mode = mode
+		case "REPEATED":
+			c.Mode = mode
+			if !isArrayType(c.TypeName) {
+				c.TypeName = "ARRAY<" + c.TypeName + ">"
+			}
+		default:
+			return nil, fmt.Errorf("column %s has unknown mode %s", c.Name, c.Mode)
+		}
+	}
+	return out, nil
+}
+
+// isArrayType reports whether a type name spells an ARRAY type.
+func isArrayType(typeName string) bool {
+	rest, ok := strings.CutPrefix(strings.ToUpper(strings.TrimSpace(typeName)), "ARRAY")
+	return ok && strings.HasPrefix(strings.TrimSpace(rest), "<")
 }
 
 func toBridgeColumns(columns []ColumnDef) []bridge.ColumnDef {
 	out := make([]bridge.ColumnDef, len(columns))
 	for i, c := range columns {
-		out[i] = bridge.ColumnDef{Name: c.Name, TypeName: c.TypeName}
+		out[i] = bridge.ColumnDef{Name: c.Name, TypeName: c.TypeName, Mode: c.Mode, Pseudo: c.Pseudo}
 	}
 	return out
 }
@@ -1,9 +1,13 @@
 package bigq_test
 
 import (
+	"errors"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/pacer/go-bigq/bigq"
+	"github.com/pacer/go-bigq/internal/bridge"
 )
 
 func TestParseStatement(t *testing.T) {
@@ -95,10 +99,843 @@ func TestAnalyzeStatement(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := bigq.AnalyzeStatement(tt.sql, cat)
+			_, err := bigq.AnalyzeStatement(tt.sql, cat)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("AnalyzeStatement(%q) error = %v, wantErr %v", tt.sql, err, tt.wantErr)
 			}
 		})
 	}
 }
+
+func TestAnalyzeStatementOutput(t *testing.T) {
+	cat, err := bigq.NewCatalog("test")
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	defer cat.Close()
+
+	err = cat.AddTable("my_table", []bigq.ColumnDef{
+		{Name: "id", TypeName: "INT64"},
+		{Name: "name", TypeName: "STRING"},
+		{Name: "created_at", TypeName: "TIMESTAMP"},
+	})
+	if err != nil {
+		t.Fatalf("AddTable: %v", err)
+	}
+
+	out, err := bigq.AnalyzeStatement("SELECT id, UPPER(name) AS upper_name FROM my_table", cat)
+	if err != nil {
+		t.Fatalf("AnalyzeStatement: %v", err)
+	}
+
+	if out.StatementKind != "QueryStmt" {
+		t.Errorf("StatementKind = %q, want %q", out.StatementKind, "QueryStmt")
+	}
+	wantCols := []bigq.ColumnDef{
+		{Name: "id", TypeName: "INT64"},
+		{Name: "upper_name", TypeName: "STRING"},
+	}
+	if len(out.OutputColumns) != len(wantCols) {
+		t.Fatalf("OutputColumns = %v, want %v", out.OutputColumns, wantCols)
+	}
+	for i, c := range wantCols {
+		if out.OutputColumns[i] != c {
+			t.Errorf("OutputColumns[%d] = %v, want %v", i, out.OutputColumns[i], c)
+		}
+	}
+	if len(out.Tables) != 1 || out.Tables[0] != "my_table" {
+		t.Errorf("Tables = %v, want [my_table]", out.Tables)
+	}
+
+	// created_at is never read, so it must not be reported.
+	for _, ref := range out.Columns {
+		if ref.Column == "created_at" {
+			t.Errorf("Columns includes unreferenced column: %v", out.Columns)
+		}
+	}
+	if len(out.Columns) != 2 {
+		t.Errorf("Columns = %v, want id and name", out.Columns)
+	}
+
+	if out.Resolved == nil || out.Resolved.Kind != "QueryStmt" {
+		t.Fatalf("Resolved root = %v, want QueryStmt", out.Resolved)
+	}
+	if scans := out.Resolved.Find("TableScan"); len(scans) != 1 || scans[0].Name != "my_table" {
+		t.Errorf("TableScan nodes = %v", scans)
+	}
+}
+
+func TestQualifiedTablePaths(t *testing.T) {
+	cat, err := bigq.NewCatalog("test")
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	defer cat.Close()
+
+	cols := []bigq.ColumnDef{{Name: "id", TypeName: "INT64"}}
+	if err := cat.AddTable("my-project.sales.orders", cols); err != nil {
+		t.Fatalf("AddTable: %v", err)
+	}
+	if err := cat.AddTable("analytics.events", cols); err != nil {
+		t.Fatalf("AddTable: %v", err)
+	}
+	if err := cat.AddTable("analytics.events", cols); err == nil {
+		t.Error("AddTable of a duplicate table succeeded")
+	}
+
+	// Tables added through nested sub-catalogs land in the same hierarchy.
+	ds := cat.AddSubCatalog("my-project").AddSubCatalog("sales")
+	if err := ds.AddTable("customers", cols); err != nil {
+		t.Fatalf("SubCatalog.AddTable: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		sql     string
+		wantErr bool
+	}{
+		{"dotted path", "SELECT id FROM `my-project`.sales.orders", false},
+		{"dashed project", "SELECT id FROM my-project.sales.orders", false},
+		{"quoted whole path", "SELECT id FROM `my-project.sales.orders`", false},
+		{"quoted project and dataset", "SELECT id FROM `my-project.sales`.orders", false},
+		{"quoted dataset and table", "SELECT id FROM `my-project`.`sales.orders`", false},
+		{"dataset table", "SELECT id FROM analytics.events", false},
+		{"quoted dataset table", "SELECT id FROM `analytics.events`", false},
+		{"sub-catalog table", "SELECT id FROM `my-project.sales.customers`", false},
+		{"missing dataset", "SELECT id FROM sales.orders", true},
+		{"wrong dataset", "SELECT id FROM `my-project`.analytics.orders", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := bigq.AnalyzeStatement(tt.sql, cat)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AnalyzeStatement(%q) error = %v, wantErr %v", tt.sql, err, tt.wantErr)
+			}
+			if err == nil && len(out.Tables) != 1 {
+				t.Errorf("Tables = %v, want one table", out.Tables)
+			}
+		})
+	}
+}
+
+func TestDefaultProjectAndDataset(t *testing.T) {
+	cat, err := bigq.NewCatalog("test",
+		bigq.WithDefaultProject("my-project"),
+		bigq.WithDefaultDataset("sales"))
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	defer cat.Close()
+
+	cols := []bigq.ColumnDef{{Name: "id", TypeName: "INT64"}}
+	for _, name := range []string{"my-project.sales.orders", "my-project.hr.staff", "other.sales.refunds"} {
+		if err := cat.AddTable(name, cols); err != nil {
+			t.Fatalf("AddTable(%s): %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		sql     string
+		wantErr bool
+	}{
+		{"fully qualified", "SELECT id FROM `my-project.sales.orders`", false},
+		{"dataset qualified", "SELECT id FROM sales.orders", false},
+		{"unqualified", "SELECT id FROM orders", false},
+		{"other dataset", "SELECT id FROM hr.staff", false},
+		{"other dataset unqualified", "SELECT id FROM staff", true},
+		{"other project", "SELECT id FROM other.sales.refunds", false},
+		{"other project unqualified", "SELECT id FROM refunds", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := bigq.AnalyzeStatement(tt.sql, cat)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AnalyzeStatement(%q) error = %v, wantErr %v", tt.sql, err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, ok := cat.Table("orders"); !ok {
+		t.Error(`Table("orders") not found under the default dataset`)
+	}
+}
+
+func TestParseScriptAST(t *testing.T) {
+	sql := "DECLARE x INT64 DEFAULT 1;\nIF x > 0 THEN\n  SELECT id FROM dataset.my_table;\nEND IF;"
+	script, err := bigq.ParseScriptAST(sql)
+	if err != nil {
+		t.Fatalf("ParseScriptAST: %v", err)
+	}
+
+	stmts := script.Statements()
+	if len(stmts) != 2 {
+		t.Fatalf("got %d statements, want 2", len(stmts))
+	}
+	if !stmts[0].IsScriptStatement() || !stmts[1].IsScriptStatement() {
+		t.Errorf("DECLARE and IF should be script statements: %s, %s", stmts[0].Kind, stmts[1].Kind)
+	}
+
+	queries := stmts[1].Find("QueryStatement")
+	if len(queries) != 1 {
+		t.Fatalf("got %d nested queries, want 1", len(queries))
+	}
+	if !queries[0].IsSQLStatement() {
+		t.Errorf("QueryStatement should be a SQL statement")
+	}
+	if got := queries[0].Text(sql); got != "SELECT id FROM dataset.my_table" {
+		t.Errorf("query text = %q", got)
+	}
+
+	tables := queries[0].Find("TablePathExpression")
+	if len(tables) != 1 {
+		t.Fatalf("got %d table paths, want 1", len(tables))
+	}
+	paths := tables[0].Find("PathExpression")
+	if len(paths) == 0 {
+		t.Fatal("table path has no PathExpression")
+	}
+	if got := strings.Join(paths[0].Path(), "."); got != "dataset.my_table" {
+		t.Errorf("table path = %q, want %q", got, "dataset.my_table")
+	}
+}
+
+func TestPosition(t *testing.T) {
+	sql := "SELECT 1;\n  SELECT 'é', x;"
+	tests := []struct {
+		offset    int
+		line, col int
+	}{
+		{0, 1, 1},
+		{7, 1, 8},
+		{12, 2, 3},
+		{strings.Index(sql, "x"), 2, 15},
+		{len(sql), 2, 17},
+	}
+	for _, tt := range tests {
+		line, col := bigq.Position(sql, tt.offset)
+		if line != tt.line || col != tt.col {
+			t.Errorf("Position(%d) = %d:%d, want %d:%d", tt.offset, line, col, tt.line, tt.col)
+		}
+	}
+}
+
+func TestParseScriptASTError(t *testing.T) {
+	if _, err := bigq.ParseScriptAST("SELECT * FORM t;"); err == nil {
+		t.Error("expected syntax error")
+	}
+}
+
+func TestParseScriptStatements(t *testing.T) {
+	sql := "SELECT 1;\nSELECT * FORM t;\nIF true THEN SELECT 2; END IF;\nSELECT (;\n-- trailing comment\n"
+	stmts := bigq.ParseScriptStatements(sql)
+
+	var errs []bigq.ScriptStatement
+	for _, s := range stmts {
+		if s.Err != nil {
+			errs = append(errs, s)
+		}
+	}
+	if len(errs) != 2 {
+		t.Fatalf("got %d failing statements, want 2: %+v", len(errs), stmts)
+	}
+	if want := strings.Index(sql, "FORM"); errs[0].ErrOffset != want {
+		t.Errorf("first error offset = %d, want %d", errs[0].ErrOffset, want)
+	}
+
+	var ifStmt *bigq.ScriptStatement
+	for i := range stmts {
+		if stmts[i].Node != nil && stmts[i].Node.Kind == "IfStatement" {
+			ifStmt = &stmts[i]
+		}
+	}
+	if ifStmt == nil {
+		t.Fatal("IF block after a syntax error was not parsed")
+	}
+	if got := sql[ifStmt.Start:ifStmt.End]; got != "IF true THEN SELECT 2; END IF" {
+		t.Errorf("IF statement text = %q", got)
+	}
+}
+
+func TestParseType(t *testing.T) {
+	tests := []struct {
+		typ     string
+		want    string
+		wantErr bool
+	}{
+		{"INT64", "INT64", false},
+		{"string(50)", "STRING(50)", false},
+		{"NUMERIC(10,2)", "NUMERIC(10, 2)", false},
+		{"BIGNUMERIC(76, 38)", "BIGNUMERIC(76, 38)", false},
+		{"RANGE<DATE>", "RANGE<DATE>", false},
+		{"ARRAY<STRUCT<`order` INT64, name STRING(10)>>", "ARRAY<STRUCT<`order` INT64, name STRING(10)>>", false},
+		{"STRUCT<a INT64 NOT NULL>", "STRUCT<a INT64>", false},
+		{"", "", true},
+		{"INT65", "", true},
+		{"STRING(0)", "", true},
+		{"NUMERIC(40, 2)", "", true},
+		{"STRUCT<a INT64", "", true},
+		{"INT64, b STRING", "", true},
+		{"INT64 DEFAULT 1", "", true},
+		{"INT64 OPTIONS(description = 'x')", "", true},
+		{"STRUCT<a INT64 OPTIONS(description = 'x')>", "", true},
+		{"STRING COLLATE 'und:ci'", "", true},
+		{"INT64 PRIMARY KEY NOT ENFORCED", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			got, err := bigq.ParseType(tt.typ)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseType(%q) error = %v, wantErr %v", tt.typ, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseType(%q) = %q, want %q", tt.typ, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTypeErrorLocation(t *testing.T) {
+	for _, typ := range []string{
+		"STRUCT<a INT64, b BOOLEN>",
+		"STRUCT<`naïve` INT64, b BOOLEN>", // columns count characters, not bytes
+	} {
+		_, err := bigq.ParseType(typ)
+		var e *bigq.Error
+		if !errors.As(err, &e) {
+			t.Fatalf("ParseType(%q) error = %v, want a *bigq.Error", typ, err)
+		}
+		offset := strings.Index(typ, "BOOLEN")
+		if e.Offset != offset {
+			t.Errorf("ParseType(%q): Offset = %d, want the offset of BOOLEN", typ, e.Offset)
+		}
+		if line, column := bigq.Position(typ, offset); e.Line != line || e.Column != column {
+			t.Errorf("ParseType(%q): position = %d:%d, want %d:%d", typ, e.Line, e.Column, line, column)
+		}
+	}
+}
+
+func TestParameterizedColumnTypes(t *testing.T) {
+	cat, err := bigq.NewCatalog("test")
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	defer cat.Close()
+
+	err = cat.AddTable("accounts", []bigq.ColumnDef{
+		{Name: "code", TypeName: "STRING(8)"},
+		{Name: "balance", TypeName: "NUMERIC(12, 2)"},
+		{Name: "active", TypeName: "RANGE<DATE>"},
+		{Name: "owner", TypeName: "STRUCT<`from` STRING, id INT64 NOT NULL>"},
+	})
+	if err != nil {
+		t.Fatalf("AddTable: %v", err)
+	}
+	if _, err := bigq.AnalyzeStatement("SELECT code, balance * 2, RANGE_START(active), owner.`from` FROM accounts", cat); err != nil {
+		t.Errorf("AnalyzeStatement: %v", err)
+	}
+
+	if err := cat.AddTable("bad", []bigq.ColumnDef{{Name: "x", TypeName: "STRING(-1)"}}); err == nil {
+		t.Error("AddTable with an invalid type parameter succeeded")
+	}
+}
+
+func TestAddRoutine(t *testing.T) {
+	cat, err := bigq.NewCatalog("test")
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	defer cat.Close()
+
+	for _, sql := range []string{
+		"CREATE FUNCTION utils.add_one(x INT64) AS (x + 1)",
+		"CREATE FUNCTION first_or_null(arr ANY TYPE) AS (arr[SAFE_OFFSET(0)])",
+		`CREATE FUNCTION js_len(s STRING) RETURNS INT64 LANGUAGE js AS "return s.length;"`,
+		"CREATE TABLE FUNCTION utils.numbers(n INT64) AS SELECT x FROM UNNEST(GENERATE_ARRAY(1, n)) AS x",
+		"CREATE TABLE FUNCTION utils.pairs(n INT64) RETURNS TABLE<a INT64, b STRING> AS SELECT n AS a, 'x' AS b",
+	} {
+		if err := cat.AddRoutine(sql); err != nil {
+			t.Fatalf("AddRoutine(%q): %v", sql, err)
+		}
+	}
+
+	for _, sql := range []string{
+		"CREATE FUNCTION bad_body(x INT64) AS (x || 'a')",
+		"CREATE FUNCTION upper(s STRING) AS (s)",
+		"CREATE FUNCTION utils.add_one(x INT64) AS (x + 2)",
+		"CREATE TABLE FUNCTION utils.numbers(n INT64) AS SELECT 1 AS x",
+		"CREATE TABLE FUNCTION bad_query(n INT64) AS SELECT missing FROM UNNEST([n])",
+		"SELECT 1",
+	} {
+		if err := cat.AddRoutine(sql); err == nil {
+			t.Errorf("AddRoutine(%q) succeeded", sql)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		sql     string
+		wantErr bool
+	}{
+		{"qualified", "SELECT utils.add_one(1)", false},
+		{"quoted", "SELECT `utils.add_one`(1)", false},
+		{"templated", "SELECT first_or_null(['a', 'b']), first_or_null([1, 2]) + 1", false},
+		{"javascript", "SELECT js_len('abc') + 1", false},
+		{"wrong argument type", "SELECT utils.add_one('a')", true},
+		{"templated body error", "SELECT first_or_null(1)", true},
+		{"unknown function", "SELECT utils.add_two(1)", true},
+		{"table function", "SELECT x + 1 FROM utils.numbers(3)", false},
+		{"table function returns", "SELECT a, b FROM `utils.pairs`(1)", false},
+		{"table function argument type", "SELECT x FROM utils.numbers('3')", true},
+		{"table function column", "SELECT y FROM utils.numbers(3)", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := bigq.AnalyzeStatement(tt.sql, cat)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AnalyzeStatement(%q) error = %v, wantErr %v", tt.sql, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAddRoutineProcedure(t *testing.T) {
+	cat, err := bigq.NewCatalog("test", bigq.WithDefaultDataset("ops"))
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	defer cat.Close()
+
+	sql := "CREATE PROCEDURE ops.refresh(IN day DATE, OUT n INT64)\nBEGIN\n  SET n = 0;\nEND"
+	if err := cat.AddRoutine(sql); err != nil {
+		t.Fatalf("AddRoutine: %v", err)
+	}
+	if err := cat.AddRoutine(sql); err == nil {
+		t.Error("AddRoutine of a duplicate procedure succeeded")
+	}
+
+	p, ok := cat.Procedure("refresh")
+	if !ok {
+		t.Fatal("Procedure(refresh) not found")
+	}
+	want := []bigq.Argument{{Name: "day", TypeName: "DATE", Mode: "IN"}, {Name: "n", TypeName: "INT64", Mode: "OUT"}}
+	if p.Name != "ops.refresh" || !slices.Equal(p.Arguments, want) || !strings.Contains(p.Body, "SET n = 0") {
+		t.Errorf("Procedure(refresh) = %+v", p)
+	}
+
+	out, err := bigq.AnalyzeStatement("CALL refresh(CURRENT_DATE(), NULL)", cat)
+	if err != nil {
+		t.Fatalf("AnalyzeStatement(CALL): %v", err)
+	}
+	if got := strings.Join(out.NamePath, "."); got != "ops.refresh" {
+		t.Errorf("CALL NamePath = %q, want ops.refresh", got)
+	}
+	if _, err := bigq.AnalyzeStatement("CALL refresh(1, NULL)", cat); err == nil {
+		t.Error("CALL with a wrong argument type succeeded")
+	}
+}
+
+func TestPseudoColumns(t *testing.T) {
+	cat, err := bigq.NewCatalog("test")
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	defer cat.Close()
+
+	err = cat.AddTable("ds.ingested", []bigq.ColumnDef{
+		{Name: "id", TypeName: "INT64"},
+		{Name: "_PARTITIONTIME", TypeName: "TIMESTAMP", Pseudo: true},
+		{Name: "_PARTITIONDATE", TypeName: "DATE", Pseudo: true},
+	})
+	if err != nil {
+		t.Fatalf("AddTable: %v", err)
+	}
+
+	out, err := bigq.AnalyzeStatement("SELECT * FROM ds.ingested", cat)
+	if err != nil {
+		t.Fatalf("AnalyzeStatement: %v", err)
+	}
+	if len(out.OutputColumns) != 1 || out.OutputColumns[0].Name != "id" {
+		t.Errorf("SELECT * columns = %+v, want only id", out.OutputColumns)
+	}
+	sql := "SELECT id, _PARTITIONTIME FROM ds.ingested WHERE _PARTITIONDATE = CURRENT_DATE()"
+	if _, err := bigq.AnalyzeStatement(sql, cat); err != nil {
+		t.Errorf("AnalyzeStatement(%q): %v", sql, err)
+	}
+}
+
+func TestWildcardTables(t *testing.T) {
+	cat, err := bigq.NewCatalog("test", bigq.WithDefaultDataset("ds"))
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	defer cat.Close()
+
+	tables := map[string][]bigq.ColumnDef{
+		"ds.events_20240101":  {{Name: "id", TypeName: "INT64"}, {Name: "name", TypeName: "STRING"}},
+		"ds.events_20240102":  {{Name: "id", TypeName: "INT64"}, {Name: "day", TypeName: "DATE"}},
+		"ds.other":            {{Name: "secret", TypeName: "STRING"}},
+		"ds2.events_20240101": {{Name: "elsewhere", TypeName: "STRING"}},
+	}
+	for name, cols := range tables {
+		if err := cat.AddTable(name, cols); err != nil {
+			t.Fatalf("AddTable(%s): %v", name, err)
+		}
+	}
+
+	out, err := bigq.AnalyzeStatement("SELECT * FROM `ds.events_*`", cat)
+	if err != nil {
+		t.Fatalf("AnalyzeStatement: %v", err)
+	}
+	var names []string
+	for _, c := range out.OutputColumns {
+		names = append(names, c.Name)
+	}
+	if want := []string{"id", "name", "day"}; !slices.Equal(names, want) {
+		t.Errorf("SELECT * columns = %v, want %v", names, want)
+	}
+
+	tests := []struct {
+		name    string
+		sql     string
+		wantErr bool
+	}{
+		{"table suffix", "SELECT id, name, day FROM `ds.events_*` WHERE _TABLE_SUFFIX BETWEEN '20240101' AND '20240131'", false},
+		{"shorter prefix", "SELECT id FROM `ds.events_2024*`", false},
+		{"default dataset", "SELECT _TABLE_SUFFIX FROM `events_*`", false},
+		{"other dataset", "SELECT elsewhere FROM `ds2.events_*`", false},
+		{"column of another table", "SELECT secret FROM `ds.events_*`", true},
+		{"no match", "SELECT 1 FROM `ds.nothing_*`", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := bigq.AnalyzeStatement(tt.sql, cat)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AnalyzeStatement(%q) error = %v, wantErr %v", tt.sql, err, tt.wantErr)
+			}
+		})
+	}
+
+	// Tables a script dropped no longer match.
+	overlay := cat.NewOverlay()
+	defer overlay.Close()
+	overlay.DropTable("ds.events_20240101")
+	if _, err := overlay.AnalyzeStatement("SELECT id, day FROM `ds.events_*`"); err != nil {
+		t.Errorf("Overlay.AnalyzeStatement after DROP: %v", err)
+	}
+	if _, err := overlay.AnalyzeStatement("SELECT name FROM `ds.events_*`"); err == nil {
+		t.Error("Overlay.AnalyzeStatement found a column of a dropped table")
+	}
+	overlay.DropTable("ds.events_20240102")
+	if _, err := overlay.AnalyzeStatement("SELECT 1 FROM `ds.events_*`"); err == nil {
+		t.Error("Overlay.AnalyzeStatement matched only dropped tables")
+	}
+}
+
+func TestInformationSchema(t *testing.T) {
+	cat, err := bigq.NewCatalog("test", bigq.WithInformationSchema(), bigq.WithDefaultDataset("ds"))
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	defer cat.Close()
+
+	tests := []struct {
+		name    string
+		sql     string
+		wantErr bool
+	}{
+		{"region jobs", "SELECT job_id, total_bytes_billed / 1e9 FROM `region-us`.INFORMATION_SCHEMA.JOBS WHERE creation_time > TIMESTAMP_SUB(CURRENT_TIMESTAMP(), INTERVAL 1 DAY)", false},
+		{"region jobs quoted", "SELECT r.table_id FROM `my-project.region-eu.INFORMATION_SCHEMA.JOBS_BY_PROJECT`, UNNEST(referenced_tables) AS r", false},
+		{"dataset columns", "SELECT column_name, ordinal_position + 1 FROM ds.INFORMATION_SCHEMA.COLUMNS WHERE table_name = 'orders'", false},
+		{"project dataset tables", "SELECT table_name, creation_time FROM `my-project.ds.INFORMATION_SCHEMA.TABLES`", false},
+		{"default dataset", "SELECT table_name FROM INFORMATION_SCHEMA.VIEWS", false},
+		{"lower case", "SELECT table_name FROM ds.information_schema.tables", false},
+		{"jobs at dataset level", "SELECT job_id FROM ds.INFORMATION_SCHEMA.JOBS", true},
+		{"unknown view", "SELECT 1 FROM ds.INFORMATION_SCHEMA.NOTHING", true},
+		{"unknown column", "SELECT nothing FROM ds.INFORMATION_SCHEMA.TABLES", true},
+		{"column type", "SELECT total_slot_ms || 'ms' FROM `region-us`.INFORMATION_SCHEMA.JOBS", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := bigq.AnalyzeStatement(tt.sql, cat)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AnalyzeStatement(%q) error = %v, wantErr %v", tt.sql, err, tt.wantErr)
+			}
+		})
+	}
+
+	plain, err := bigq.NewCatalog("plain")
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	defer plain.Close()
+	if _, err := bigq.AnalyzeStatement("SELECT table_name FROM ds.INFORMATION_SCHEMA.TABLES", plain); err == nil {
+		t.Error("INFORMATION_SCHEMA resolved without WithInformationSchema")
+	}
+
+	// Without a default dataset a view needs a dataset or region, and a
+	// project alone is neither.
+	project, err := bigq.NewCatalog("project", bigq.WithInformationSchema(), bigq.WithDefaultProject("my-project"))
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	defer project.Close()
+	for sql, wantErr := range map[string]bool{
+		"SELECT table_name FROM INFORMATION_SCHEMA.TABLES":                 true,
+		"SELECT table_name FROM `my-project`.INFORMATION_SCHEMA.TABLES":    true,
+		"SELECT table_name FROM `my-project`.ds.INFORMATION_SCHEMA.TABLES": false,
+		"SELECT table_name FROM ds.INFORMATION_SCHEMA.TABLES":              false,
+		"SELECT job_id FROM `region-us`.INFORMATION_SCHEMA.JOBS":           false,
+	} {
+		if _, err := bigq.AnalyzeStatement(sql, project); (err != nil) != wantErr {
+			t.Errorf("AnalyzeStatement(%q) error = %v, wantErr %v", sql, err, wantErr)
+		}
+	}
+}
+
+func TestQueryParameters(t *testing.T) {
+	cat, err := bigq.NewCatalog("test")
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	defer cat.Close()
+	if err := cat.AddTable("orders", []bigq.ColumnDef{{Name: "id", TypeName: "INT64"}, {Name: "day", TypeName: "DATE"}}); err != nil {
+		t.Fatalf("AddTable: %v", err)
+	}
+	if err := cat.AddParameter("@start_date", "DATE"); err != nil {
+		t.Fatalf("AddParameter: %v", err)
+	}
+	if err := cat.AddParameter("ids", "ARRAY<INT64>"); err != nil {
+		t.Fatalf("AddParameter: %v", err)
+	}
+	if err := cat.AddParameter("bad", "NOT_A_TYPE"); err == nil {
+		t.Error("AddParameter with an unknown type succeeded")
+	}
+	if err := cat.AddPositionalParameter("INT64"); err == nil {
+		t.Error("AddPositionalParameter succeeded after named parameters")
+	}
+
+	for _, tt := range []struct {
+		sql     string
+		wantErr bool
+	}{
+		{"SELECT id FROM orders WHERE day >= @start_date AND id IN UNNEST(@ids)", false},
+		{"SELECT id FROM orders WHERE day >= @START_DATE", false},
+		{"SELECT id FROM orders WHERE id = @start_date", true},
+		{"SELECT id FROM orders WHERE day = @end_date", true},
+		{"SELECT id FROM orders WHERE id = ?", true},
+	} {
+		if _, err := bigq.AnalyzeStatement(tt.sql, cat); (err != nil) != tt.wantErr {
+			t.Errorf("AnalyzeStatement(%q) error = %v, wantErr %v", tt.sql, err, tt.wantErr)
+		}
+	}
+
+	positional, err := bigq.NewCatalog("positional")
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	defer positional.Close()
+	for _, typeName := range []string{"INT64", "STRING"} {
+		if err := positional.AddPositionalParameter(typeName); err != nil {
+			t.Fatalf("AddPositionalParameter: %v", err)
+		}
+	}
+	if _, err := bigq.AnalyzeStatement("SELECT ? + 1, CONCAT(?, 'x')", positional); err != nil {
+		t.Errorf("AnalyzeStatement with positional parameters: %v", err)
+	}
+}
+
+func TestUndeclaredParameters(t *testing.T) {
+	cat, err := bigq.NewCatalog("test", bigq.WithUndeclaredParameters())
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	defer cat.Close()
+	if err := cat.AddTable("orders", []bigq.ColumnDef{{Name: "id", TypeName: "INT64"}, {Name: "day", TypeName: "DATE"}}); err != nil {
+		t.Fatalf("AddTable: %v", err)
+	}
+
+	out, err := bigq.AnalyzeStatement("SELECT id FROM orders WHERE day >= @start AND id = @id", cat)
+	if err != nil {
+		t.Fatalf("AnalyzeStatement: %v", err)
+	}
+	want := []bigq.Parameter{{Name: "id", TypeName: "INT64"}, {Name: "start", TypeName: "DATE"}}
+	if !slices.Equal(out.UndeclaredParameters, want) {
+		t.Errorf("UndeclaredParameters = %+v, want %+v", out.UndeclaredParameters, want)
+	}
+
+	out, err = bigq.AnalyzeStatement("SELECT id FROM orders WHERE id = ? AND day < ?", cat)
+	if err != nil {
+		t.Fatalf("AnalyzeStatement: %v", err)
+	}
+	want = []bigq.Parameter{{Position: 1, TypeName: "INT64"}, {Position: 2, TypeName: "DATE"}}
+	if !slices.Equal(out.UndeclaredParameters, want) {
+		t.Errorf("UndeclaredParameters = %+v, want %+v", out.UndeclaredParameters, want)
+	}
+
+	// The mode a statement is analyzed with does not carry over to the next.
+	out, err = bigq.AnalyzeStatement("SELECT id FROM orders WHERE id = @id", cat)
+	if err != nil {
+		t.Fatalf("AnalyzeStatement: %v", err)
+	}
+	want = []bigq.Parameter{{Name: "id", TypeName: "INT64"}}
+	if !slices.Equal(out.UndeclaredParameters, want) {
+		t.Errorf("UndeclaredParameters = %+v, want %+v", out.UndeclaredParameters, want)
+	}
+
+	if _, err := bigq.AnalyzeStatement("SELECT id FROM orders WHERE id = @x AND day = @x", cat); err == nil {
+		t.Error("AnalyzeStatement accepted a parameter used as two types")
+	}
+}
+
+func TestSystemVariables(t *testing.T) {
+	cat, err := bigq.NewCatalog("test")
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	defer cat.Close()
+
+	for _, tt := range []struct {
+		sql     string
+		wantErr bool
+	}{
+		{"SELECT @@project_id, @@dataset_id, @@current_job_id, @@time_zone", false},
+		{"SELECT @@row_count + 1, @@script.bytes_processed, @@script.job_id", false},
+		{"SELECT TIMESTAMP_DIFF(CURRENT_TIMESTAMP(), @@script.creation_time, SECOND)", false},
+		{"SELECT @@error.message, @@error.stack_trace[SAFE_OFFSET(0)].line", false},
+		{"SELECT @@row_count || 'rows'", true},
+		{"SELECT @@no_such_variable", true},
+	} {
+		if _, err := bigq.AnalyzeStatement(tt.sql, cat); (err != nil) != tt.wantErr {
+			t.Errorf("AnalyzeStatement(%q) error = %v, wantErr %v", tt.sql, err, tt.wantErr)
+		}
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	cat, err := bigq.NewCatalog("test")
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	defer cat.Close()
+	if err := cat.AddTable("my_table", []bigq.ColumnDef{{Name: "id", TypeName: "INT64"}, {Name: "name", TypeName: "STRING"}}); err != nil {
+		t.Fatalf("AddTable: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		sql  string
+		kind bigq.ErrorKind
+	}{
+		{"unknown table", "SELECT id FROM missing", bigq.KindUnknownTable},
+		{"unknown insert target", "INSERT INTO missing (id) VALUES (1)", bigq.KindUnknownTable},
+		{"unknown column", "SELECT missing FROM my_table", bigq.KindUnknownColumn},
+		{"unknown qualified column", "SELECT my_table.missing FROM my_table", bigq.KindUnknownColumn},
+		{"unknown field", "SELECT STRUCT(1 AS a).b", bigq.KindUnknownColumn},
+		{"unknown function", "SELECT no_such_function(id) FROM my_table", bigq.KindUnknownFunction},
+		{"unknown procedure", "CALL no_such_procedure()", bigq.KindUnknownFunction},
+		{"unknown type", "SELECT CAST(id AS BOOLEN) FROM my_table", bigq.KindUnknownType},
+		{"unknown parameter", "SELECT id FROM my_table WHERE id = @missing", bigq.KindUnknownParameter},
+		{"signature mismatch", "SELECT id FROM my_table WHERE name = 1", bigq.KindSignatureMismatch},
+		{"function signature mismatch", "SELECT LENGTH(id, id) FROM my_table", bigq.KindSignatureMismatch},
+		{"type mismatch", "INSERT INTO my_table (id) VALUES ('a')", bigq.KindTypeMismatch},
+		{"invalid cast", "SELECT CAST(STRUCT(1 AS a) AS INT64)", bigq.KindTypeMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := bigq.AnalyzeStatement(tt.sql, cat)
+			var e *bigq.Error
+			if !errors.As(err, &e) {
+				t.Fatalf("AnalyzeStatement(%q) error = %v, want a *bigq.Error", tt.sql, err)
+			}
+			if e.Kind != tt.kind {
+				t.Errorf("Kind = %q, want %q (%s)", e.Kind, tt.kind, e.Message)
+			}
+			if e.Code != 3 { // INVALID_ARGUMENT
+				t.Errorf("Code = %d, want 3", e.Code)
+			}
+			if e.Line != 1 || e.Column < 1 || e.Offset != e.Column-1 {
+				t.Errorf("position = %d:%d, offset %d", e.Line, e.Column, e.Offset)
+			}
+			if e.Message == "" || strings.HasPrefix(e.Message, "1:") {
+				t.Errorf("Message = %q, want the message without its position", e.Message)
+			}
+			if err.Error() != e.Message {
+				t.Errorf("Error() = %q, want the message %q", err.Error(), e.Message)
+			}
+		})
+	}
+}
+
+func TestSyntaxErrorKind(t *testing.T) {
+	err := bigq.ParseStatement("SELECT * FORM my_table")
+	var e *bigq.Error
+	if !errors.As(err, &e) {
+		t.Fatalf("ParseStatement error = %v, want a *bigq.Error", err)
+	}
+	if e.Kind != bigq.KindSyntax || e.Line != 1 || e.Column != 10 {
+		t.Errorf("error = %q at %d:%d, want %q at 1:10", e.Kind, e.Line, e.Column, bigq.KindSyntax)
+	}
+	if err.Error() != e.Message {
+		t.Errorf("Error() = %q, want the message %q", err.Error(), e.Message)
+	}
+	// The status stays reachable for callers that used it before.
+	var status bridge.Status
+	if !errors.As(err, &status) {
+		t.Errorf("ParseStatement error %v does not wrap a bridge.Status", err)
+	}
+}
+
+func TestNames(t *testing.T) {
+	cat, err := bigq.NewCatalog("test")
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	defer cat.Close()
+	for _, name := range []string{"ds.Orders", "ds.customers"} {
+		if err := cat.AddTable(name, []bigq.ColumnDef{{Name: "id", TypeName: "INT64"}}); err != nil {
+			t.Fatalf("AddTable(%s): %v", name, err)
+		}
+	}
+	if err := cat.AddRoutine("CREATE FUNCTION ds.add_one(x INT64) AS (x + 1)"); err != nil {
+		t.Fatalf("AddRoutine: %v", err)
+	}
+
+	if got, want := cat.TableNames(), []string{"ds.Orders", "ds.customers"}; !slices.Equal(got, want) {
+		t.Errorf("TableNames() = %v, want %v", got, want)
+	}
+	functions := cat.FunctionNames()
+	for _, want := range []string{"concat", "ds.add_one"} {
+		if !slices.Contains(functions, want) {
+			t.Errorf("FunctionNames() lacks %s", want)
+		}
+	}
+	if slices.ContainsFunc(functions, func(name string) bool { return strings.HasPrefix(name, "$") }) {
+		t.Error("FunctionNames() includes operators")
+	}
+	if builtins := cat.BuiltinFunctionNames(); !slices.Contains(builtins, "concat") || slices.Contains(builtins, "ds.add_one") {
+		t.Errorf("BuiltinFunctionNames() = %v, want concat and not ds.add_one among them", builtins)
+	}
+	if keywords := bigq.Keywords(); !slices.Contains(keywords, "SELECT") || !slices.Contains(keywords, "FROM") {
+		t.Errorf("Keywords() = %v, want SELECT and FROM among them", keywords)
+	}
+
+	overlay := cat.NewOverlay()
+	defer overlay.Close()
+	overlay.DropTable("ds.customers")
+	if err := overlay.AddTable("ds.staging", []bigq.ColumnDef{{Name: "id", TypeName: "INT64"}}); err != nil {
+		t.Fatalf("Overlay.AddTable: %v", err)
+	}
+	if err := overlay.AddRoutine("CREATE TEMP FUNCTION double_it(x INT64) AS (x * 2)"); err != nil {
+		t.Fatalf("Overlay.AddRoutine: %v", err)
+	}
+	if got, want := overlay.TableNames(), []string{"ds.staging", "ds.Orders"}; !slices.Equal(got, want) {
+		t.Errorf("Overlay.TableNames() = %v, want %v", got, want)
+	}
+	if !slices.Contains(overlay.FunctionNames(), "double_it") {
+		t.Error("Overlay.FunctionNames() lacks the script's function")
+	}
+}
@@ -0,0 +1,88 @@
+package bigq
+
+import (
+	"errors"
+
+	"github.com/pacer/go-bigq/internal/bridge"
+)
+
+// ErrorKind classifies an Error. Its values are stable and can be used as
+// error codes, e.g. in lint output.
+type ErrorKind string
+
+// Error kinds.
+const (
+	KindSyntax            ErrorKind = "syntax"
+	KindUnknownTable      ErrorKind = "unknown-table"
+	KindUnknownColumn     ErrorKind = "unknown-column"
+	KindUnknownFunction   ErrorKind = "unknown-function" // also table functions and procedures
+	KindUnknownType       ErrorKind = "unknown-type"
+	KindUnknownParameter  ErrorKind = "unknown-parameter"
+	KindTypeMismatch      ErrorKind = "type-mismatch"
+	KindSignatureMismatch ErrorKind = "signature-mismatch"
+	KindAnalysis          ErrorKind = "analysis" // any other analysis error
+)
+
+// Error is a syntax or analysis error reported by ZetaSQL. Functions of
+// this package that parse or analyze SQL return one, which can be
+// retrieved with errors.As.
+type Error struct {
+	Kind ErrorKind
+
+	// Code is the ZetaSQL status code, e.g. 3 for INVALID_ARGUMENT.
+	Code int
+
+	// Message is ZetaSQL's message, without the position.
+	Message string
+
+	// Line and Column are the 1-based position of the error in the parsed
+	// or analyzed text, 0 if ZetaSQL reported none; Offset is the same
+	// position as a byte offset, -1 if unknown.
+	Line   int
+	Column int
+	Offset int
+
+	err error
+}
+
+// Error returns the message. The position is in Line and Column, relative
+// to the text that was parsed or analyzed, which callers know better how to
+// report.
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.err }
+
+// sourceKinds map the source the bridge reports for an error to its kind.
+// Sources not listed are KindAnalysis.
+var sourceKinds = map[int]ErrorKind{
+	bridge.ErrorSyntax:            KindSyntax,
+	bridge.ErrorUnknownTable:      KindUnknownTable,
+	bridge.ErrorUnknownColumn:     KindUnknownColumn,
+	bridge.ErrorUnknownFunction:   KindUnknownFunction,
+	bridge.ErrorUnknownType:       KindUnknownType,
+	bridge.ErrorUnknownParameter:  KindUnknownParameter,
+	bridge.ErrorTypeMismatch:      KindTypeMismatch,
+	bridge.ErrorSignatureMismatch: KindSignatureMismatch,
+}
+
+// newError returns err as an *Error if it wraps a ZetaSQL status, and
+// unchanged otherwise.
+func newError(err error) error {
+	var status bridge.Status
+	if err == nil || !errors.As(err, &status) {
+		return err
+	}
+	kind, ok := sourceKinds[status.Source]
+	if !ok {
+		kind = KindAnalysis
+	}
+	return &Error{
+		Kind:    kind,
+		Code:    status.Code,
+		Message: status.ErrorMessage,
+		Line:    status.ErrorLine,
+		Column:  status.ErrorColumn,
+		Offset:  status.ErrorOffset,
+		err:     err,
+	}
+}
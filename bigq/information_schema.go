@@ -0,0 +1,322 @@
+package bigq
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pacer/go-bigq/internal/bridge"
+)
+
+const informationSchema = "INFORMATION_SCHEMA"
+
+func isInformationSchema(part string) bool {
+	return strings.EqualFold(part, informationSchema)
+}
+
+// Levels an INFORMATION_SCHEMA view can be queried at.
+const (
+	datasetLevel = 1 << iota // dataset.INFORMATION_SCHEMA.VIEW
+	regionLevel              // `region-us`.INFORMATION_SCHEMA.VIEW
+)
+
+type informationSchemaView struct {
+	levels  int
+	columns string // one "name TYPE" per line
+}
+
+// informationSchemaTable is the table of an INFORMATION_SCHEMA view.
+type informationSchemaTable struct {
+	levels int
+	table  *bridge.Table
+}
+
+// loadInformationSchema builds the tables of the INFORMATION_SCHEMA views.
+func (c *Catalog) loadInformationSchema() error {
+	c.viewCatalog = bridge.NewSimpleCatalog(informationSchema, c.factory)
+	c.views = make(map[string]informationSchemaTable, len(informationSchemaViews))
+	for name, view := range informationSchemaViews {
+		var columns []ColumnDef
+		for _, line := range strings.Split(strings.TrimSpace(view.columns), "\n") {
+			column, typeName, _ := strings.Cut(strings.TrimSpace(line), " ")
+			columns = append(columns, ColumnDef{Name: column, TypeName: typeName})
+		}
+		table, err := bridge.NewTable(informationSchema+"."+name, toBridgeColumns(columns), c.inner, c.opts)
+		if err != nil {
+			return fmt.Errorf("INFORMATION_SCHEMA.%s: %w", name, err)
+		}
+		c.viewCatalog.AddTable(name, table)
+		c.views[name] = informationSchemaTable{view.levels, table}
+	}
+	return nil
+}
+
+// informationSchemaTable returns the table of name if it is an
+// INFORMATION_SCHEMA view that can be queried at the level name qualifies
+// it with: a region (`region-us`.INFORMATION_SCHEMA.JOBS) or a dataset,
+// which may be left to the default dataset (INFORMATION_SCHEMA.TABLES).
+// Either may be qualified by a project; a project alone
+// (project.INFORMATION_SCHEMA.TABLES) does not qualify a view.
+func (c *Catalog) informationSchemaTable(name string) (*bridge.Table, bool) {
+	if c.views == nil {
+		return nil, false
+	}
+	parts := strings.Split(name, ".")
+	n := len(parts)
+	if n < 2 || n > 4 || !isInformationSchema(parts[n-2]) {
+		return nil, false
+	}
+	view, ok := c.views[strings.ToUpper(parts[n-1])]
+	if !ok {
+		return nil, false
+	}
+	level := datasetLevel
+	switch {
+	case n == 2:
+		if c.defaultDataset == "" {
+			return nil, false
+		}
+	case strings.HasPrefix(strings.ToLower(parts[n-3]), "region-"):
+		level = regionLevel
+	case n == 3 && c.isProject(parts[0]):
+		return nil, false
+	}
+	if view.levels&level == 0 {
+		return nil, false
+	}
+	return view.table, true
+}
+
+// isProject reports whether name is known to be a project and not a
+// dataset: it is the default project, or the project of the default
+// dataset or of a table, and no dataset is known by that name.
+func (c *Catalog) isProject(name string) bool {
+	var projects, datasets []string
+	if c.defaultProject != "" {
+		projects = append(projects, c.defaultProject)
+	}
+	if c.defaultDataset != "" {
+		parts := strings.Split(c.defaultDataset, ".")
+		datasets = append(datasets, parts[len(parts)-1])
+		if len(parts) == 2 {
+			projects = append(projects, parts[0])
+		}
+	}
+	for _, table := range c.tableNames {
+		switch parts := strings.Split(table, "."); len(parts) {
+		case 2:
+			datasets = append(datasets, parts[0])
+		case 3:
+			projects = append(projects, parts[0])
+			datasets = append(datasets, parts[1])
+		}
+	}
+	equal := func(s string) bool { return strings.EqualFold(s, name) }
+	return slices.ContainsFunc(projects, equal) && !slices.ContainsFunc(datasets, equal)
+}
+
+const tableNameColumns = `
+table_catalog STRING
+table_schema STRING
+table_name STRING`
+
+const routineNameColumns = `
+specific_catalog STRING
+specific_schema STRING
+specific_name STRING`
+
+const errorResultType = "STRUCT<reason STRING, location STRING, debug_info STRING, message STRING>"
+
+const tableReferenceType = "STRUCT<project_id STRING, dataset_id STRING, table_id STRING>"
+
+const jobsColumns = `
+creation_time TIMESTAMP
+project_id STRING
+project_number INT64
+folder_numbers ARRAY<INT64>
+user_email STRING
+principal_subject STRING
+job_id STRING
+job_type STRING
+statement_type STRING
+priority STRING
+start_time TIMESTAMP
+end_time TIMESTAMP
+query STRING
+state STRING
+reservation_id STRING
+edition STRING
+total_bytes_processed INT64
+total_bytes_billed INT64
+total_modified_partitions INT64
+total_slot_ms INT64
+transferred_bytes INT64
+cache_hit BOOL
+error_result ` + errorResultType + `
+destination_table ` + tableReferenceType + `
+referenced_tables ARRAY<` + tableReferenceType + `>
+labels ARRAY<STRUCT<key STRING, value STRING>>
+timeline ARRAY<STRUCT<elapsed_ms INT64, total_slot_ms INT64, pending_units INT64, completed_units INT64, active_units INT64, estimated_runnable_units INT64>>
+job_stages ARRAY<STRUCT<name STRING, id INT64, start_ms INT64, end_ms INT64, input_stages ARRAY<INT64>, wait_ratio_avg FLOAT64, wait_ms_avg INT64, wait_ratio_max FLOAT64, wait_ms_max INT64, read_ratio_avg FLOAT64, read_ms_avg INT64, read_ratio_max FLOAT64, read_ms_max INT64, compute_ratio_avg FLOAT64, compute_ms_avg INT64, compute_ratio_max FLOAT64, compute_ms_max INT64, write_ratio_avg FLOAT64, write_ms_avg INT64, write_ratio_max FLOAT64, write_ms_max INT64, shuffle_output_bytes INT64, shuffle_output_bytes_spilled INT64, records_read INT64, records_written INT64, parallel_inputs INT64, completed_parallel_inputs INT64, status STRING, steps ARRAY<STRUCT<kind STRING, substeps ARRAY<STRING>>>, slot_ms INT64, compute_mode STRING>>
+dml_statistics STRUCT<inserted_row_count INT64, deleted_row_count INT64, updated_row_count INT64>
+session_info STRUCT<session_id STRING>
+transaction_id STRING
+parent_job_id STRING
+bi_engine_statistics STRUCT<bi_engine_mode STRING, acceleration_mode STRING, bi_engine_reasons ARRAY<STRUCT<code STRING, message STRING>>>
+query_info STRUCT<resource_warning STRING, optimization_details JSON, query_hashes STRUCT<normalized_literals STRING>>
+materialized_view_statistics STRUCT<materialized_view ARRAY<STRUCT<chosen BOOL, estimated_bytes_saved INT64, rejected_reason STRING, table_reference ` + tableReferenceType + `>>>
+job_creation_reason STRUCT<code STRING>
+query_dialect STRING
+continuous BOOL`
+
+const jobsTimelineColumns = `
+period_start TIMESTAMP
+period_slot_ms INT64
+period_shuffle_ram_usage_ratio FLOAT64
+period_estimated_runnable_units INT64
+project_id STRING
+project_number INT64
+folder_numbers ARRAY<INT64>
+user_email STRING
+principal_subject STRING
+job_id STRING
+job_type STRING
+statement_type STRING
+priority STRING
+job_creation_time TIMESTAMP
+job_start_time TIMESTAMP
+job_end_time TIMESTAMP
+state STRING
+reservation_id STRING
+edition STRING
+total_bytes_billed INT64
+total_bytes_processed INT64
+error_result ` + errorResultType + `
+cache_hit BOOL
+transaction_id STRING`
+
+// informationSchemaViews are the documented INFORMATION_SCHEMA views,
+// keyed by upper-cased name.
+var informationSchemaViews = map[string]informationSchemaView{
+	"SCHEMATA": {regionLevel, `
+catalog_name STRING
+schema_name STRING
+schema_owner STRING
+creation_time TIMESTAMP
+last_modified_time TIMESTAMP
+location STRING
+ddl STRING
+default_collation_name STRING`},
+	"SCHEMATA_OPTIONS": {regionLevel, `
+catalog_name STRING
+schema_name STRING
+option_name STRING
+option_type STRING
+option_value STRING`},
+	"TABLES": {datasetLevel | regionLevel, tableNameColumns + `
+table_type STRING
+is_insertable_into STRING
+is_typed STRING
+is_change_history_enabled STRING
+creation_time TIMESTAMP
+base_table_catalog STRING
+base_table_schema STRING
+base_table_name STRING
+snapshot_time_ms TIMESTAMP
+ddl STRING
+default_collation_name STRING
+upsert_stream_apply_watermark TIMESTAMP
+replica_source_catalog STRING
+replica_source_schema STRING
+replica_source_name STRING
+replication_status STRING
+replication_error STRING`},
+	"TABLE_OPTIONS": {datasetLevel | regionLevel, tableNameColumns + `
+option_name STRING
+option_type STRING
+option_value STRING`},
+	"COLUMNS": {datasetLevel | regionLevel, tableNameColumns + `
+column_name STRING
+ordinal_position INT64
+is_nullable STRING
+data_type STRING
+is_generated STRING
+generation_expression STRING
+is_stored STRING
+is_hidden STRING
+is_updatable STRING
+is_system_defined STRING
+is_partitioning_column STRING
+clustering_ordinal_position INT64
+collation_name STRING
+column_default STRING
+rounding_mode STRING`},
+	"COLUMN_FIELD_PATHS": {datasetLevel | regionLevel, tableNameColumns + `
+column_name STRING
+field_path STRING
+data_type STRING
+description STRING
+collation_name STRING
+rounding_mode STRING`},
+	"VIEWS": {datasetLevel | regionLevel, tableNameColumns + `
+view_definition STRING
+check_option STRING
+use_standard_sql STRING`},
+	"PARTITIONS": {datasetLevel, tableNameColumns + `
+partition_id STRING
+total_rows INT64
+total_logical_bytes INT64
+total_billable_bytes INT64
+last_modified_time TIMESTAMP
+storage_tier STRING`},
+	"TABLE_STORAGE": {regionLevel, `
+project_id STRING
+project_number INT64` + tableNameColumns + `
+creation_time TIMESTAMP
+total_rows INT64
+total_partitions INT64
+total_logical_bytes INT64
+active_logical_bytes INT64
+long_term_logical_bytes INT64
+current_physical_bytes INT64
+total_physical_bytes INT64
+active_physical_bytes INT64
+long_term_physical_bytes INT64
+time_travel_physical_bytes INT64
+fail_safe_physical_bytes INT64
+storage_last_modified_time TIMESTAMP
+deleted BOOL
+table_type STRING`},
+	"ROUTINES": {datasetLevel | regionLevel, routineNameColumns + `
+routine_catalog STRING
+routine_schema STRING
+routine_name STRING
+routine_type STRING
+data_type STRING
+routine_body STRING
+routine_definition STRING
+external_language STRING
+is_deterministic STRING
+security_type STRING
+created TIMESTAMP
+last_altered TIMESTAMP
+ddl STRING
+connection STRING`},
+	"PARAMETERS": {datasetLevel | regionLevel, routineNameColumns + `
+ordinal_position INT64
+parameter_mode STRING
+is_result STRING
+parameter_name STRING
+data_type STRING
+parameter_default STRING
+is_aggregate STRING`},
+	"JOBS":                          {regionLevel, jobsColumns},
+	"JOBS_BY_USER":                  {regionLevel, jobsColumns},
+	"JOBS_BY_PROJECT":               {regionLevel, jobsColumns},
+	"JOBS_BY_FOLDER":                {regionLevel, jobsColumns},
+	"JOBS_BY_ORGANIZATION":          {regionLevel, jobsColumns},
+	"JOBS_TIMELINE":                 {regionLevel, jobsTimelineColumns},
+	"JOBS_TIMELINE_BY_USER":         {regionLevel, jobsTimelineColumns},
+	"JOBS_TIMELINE_BY_FOLDER":       {regionLevel, jobsTimelineColumns},
+	"JOBS_TIMELINE_BY_ORGANIZATION": {regionLevel, jobsTimelineColumns},
+}
@@ -0,0 +1,396 @@
+package bigq
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pacer/go-bigq/internal/bridge"
+)
+
+// Overlay layers script-local state, such as declared variables and
+// tables created by the script, over a Catalog. Names are looked up in the
+// overlay first and then in the base catalog, which is never modified. An
+// overlay is meant to live for the analysis of a single script.
+type Overlay struct {
+	base     *Catalog
+	vars     []variable
+	tables   []overlayTable
+	routines []overlayRoutine
+
+	// dropped holds the lower-cased names of base tables dropped by the
+	// script. They cannot be hidden from the analyzer, so callers check
+	// references against Dropped instead.
+	dropped map[string]bool
+
+	// inner and lookup hold the Go-side state. Additions go straight into
+	// them; anything else, such as a dropped or replaced table, marks them
+	// dirty and they are rebuilt, since ZetaSQL catalogs cannot remove
+	// entries. built holds the routines registered in inner, procedures
+	// the procedures among them and functions the names of the others.
+	inner      *bridge.SimpleCatalog
+	lookup     *bridge.MultiCatalog
+	built      []*bridge.Routine
+	procedures map[string]*Procedure
+	functions  []string
+	dirty      bool
+
+	// broken holds the routines that failed a rebuild since the last call
+	// to RoutineErrors.
+	broken []*RoutineError
+}
+
+type variable struct {
+	name     string
+	typeName string
+}
+
+type overlayTable struct {
+	name    string
+	columns []ColumnDef
+}
+
+// overlayRoutine is a CREATE [TABLE] FUNCTION or CREATE PROCEDURE
+// statement of the script and the name of the routine it defines.
+type overlayRoutine struct {
+	sql  string
+	name string
+}
+
+// RoutineError reports a routine the script created that no longer
+// analyzes after a later change, such as the DROP of a table it reads. The
+// routine is removed from the overlay, so that it does not fail the
+// analysis of statements that do not call it.
+type RoutineError struct {
+	Name string
+	Err  error
+}
+
+func (e *RoutineError) Error() string {
+	return fmt.Sprintf("%s no longer analyzes and is dropped: %s", e.Name, e.Err)
+}
+
+func (e *RoutineError) Unwrap() error { return e.Err }
+
+// NewOverlay returns an empty overlay over c.
+func (c *Catalog) NewOverlay() *Overlay {
+	return &Overlay{base: c, dropped: make(map[string]bool), dirty: true}
+}
+
+// AddTable adds a table to the overlay, replacing any overlay table of the
+// same name and shadowing a base table. Names are case-insensitive.
+func (o *Overlay) AddTable(name string, columns []ColumnDef) error {
+	columns, err := normalizeColumns(columns)
+	if err != nil {
+		return fmt.Errorf("table %s: %w", name, err)
+	}
+	// Build now so that a bad column type is reported here rather than by
+	// the next analysis.
+	if i := o.tableIndex(name); i >= 0 {
+		previous := o.tables[i]
+		o.tables[i] = overlayTable{name: name, columns: columns}
+		o.dirty = true
+		if _, err := o.catalog(); err != nil {
+			o.tables[i] = previous
+			o.dirty = true
+			return err
+		}
+	} else {
+		if _, err := o.catalog(); err != nil {
+			return err
+		}
+		if err := addTable(o.inner, o.base.opts, name, columns); err != nil {
+			o.dirty = true // it may be registered under some of its paths
+			return err
+		}
+		o.tables = append(o.tables, overlayTable{name: name, columns: columns})
+	}
+	if key, ok := o.base.resolveTable(name); ok {
+		delete(o.dropped, key)
+	}
+	return nil
+}
+
+// DropTable removes a table. Overlay tables are removed outright; base
+// tables are recorded as dropped (see Dropped).
+func (o *Overlay) DropTable(name string) {
+	if i := o.tableIndex(name); i >= 0 {
+		o.tables = append(o.tables[:i], o.tables[i+1:]...)
+		o.dirty = true
+	}
+	if key, ok := o.base.resolveTable(name); ok {
+		o.dropped[key] = true
+	}
+}
+
+// Dropped reports whether name is a base table the script has dropped and
+// not re-created.
+func (o *Overlay) Dropped(name string) bool {
+	key, ok := o.base.resolveTable(name)
+	return ok && o.dropped[key]
+}
+
+// Table returns the columns of a table as the script currently sees it:
+// an overlay table if there is one, otherwise a base table that has not
+// been dropped.
+func (o *Overlay) Table(name string) ([]ColumnDef, bool) {
+	if i := o.tableIndex(name); i >= 0 {
+		return o.tables[i].columns, true
+	}
+	if o.Dropped(name) {
+		return nil, false
+	}
+	return o.base.Table(name)
+}
+
+// TableNames returns the names of the tables the script currently sees:
+// its own, then the base tables it has not dropped or shadowed.
+func (o *Overlay) TableNames() []string {
+	var names []string
+	for _, t := range o.tables {
+		names = append(names, t.name)
+	}
+	for _, name := range o.base.TableNames() {
+		if o.tableIndex(name) < 0 && !o.Dropped(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (o *Overlay) tableIndex(name string) int {
+	for i, t := range o.tables {
+		if strings.EqualFold(t.name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// AddRoutine adds the function defined by a CREATE [TEMP] FUNCTION or
+// CREATE TABLE FUNCTION statement, or the procedure defined by a CREATE
+// PROCEDURE statement, as Catalog.AddRoutine does, for the rest of the
+// script.
+func (o *Overlay) AddRoutine(sql string) error {
+	if _, err := o.catalog(); err != nil {
+		return err
+	}
+	name, err := o.addRoutine(sql)
+	if err != nil {
+		return err
+	}
+	o.routines = append(o.routines, overlayRoutine{sql: sql, name: name})
+	return nil
+}
+
+// RoutineErrors rebuilds the overlay if it changed and returns the routines
+// dropped since the last call because they no longer analyze.
+func (o *Overlay) RoutineErrors() []*RoutineError {
+	o.catalog()
+	broken := o.broken
+	o.broken = nil
+	return broken
+}
+
+// Procedure returns a procedure the script created, or else one of the
+// base catalog.
+func (o *Overlay) Procedure(name string) (*Procedure, bool) {
+	if _, err := o.catalog(); err == nil {
+		if p, ok := o.procedures[strings.ToLower(name)]; ok {
+			return p, true
+		}
+	}
+	return o.base.Procedure(name)
+}
+
+// FunctionNames returns the names of the base catalog's functions, as
+// Catalog.FunctionNames does, followed by those the script created.
+func (o *Overlay) FunctionNames() []string {
+	names := o.base.FunctionNames()
+	if _, err := o.catalog(); err == nil {
+		names = append(names, o.functions...)
+	}
+	return names
+}
+
+// DeclareVariable makes a script variable visible to later analysis as a
+// bare identifier of the given type. Names are case-insensitive. An empty
+// typeName declares a variable of unknown type, e.g. one whose DEFAULT
+// failed analysis: it counts as declared, but analysis cannot resolve it.
+func (o *Overlay) DeclareVariable(name, typeName string) error {
+	if o.variableIndex(name) >= 0 {
+		return fmt.Errorf("variable %s is already declared", name)
+	}
+	o.vars = append(o.vars, variable{name: name, typeName: typeName})
+	if typeName != "" && !o.dirty {
+		if err := o.inner.AddConstant(name, typeName, o.base.opts); err != nil {
+			o.dirty = true // the rebuild reports it
+		}
+	}
+	return nil
+}
+
+// DropVariable removes a script variable, e.g. when the block that declared
+// it ends.
+func (o *Overlay) DropVariable(name string) {
+	if i := o.variableIndex(name); i >= 0 {
+		o.vars = append(o.vars[:i], o.vars[i+1:]...)
+		o.dirty = true
+	}
+}
+
+// VariableType returns the type of a declared variable.
+func (o *Overlay) VariableType(name string) (string, bool) {
+	if i := o.variableIndex(name); i >= 0 {
+		return o.vars[i].typeName, true
+	}
+	return "", false
+}
+
+func (o *Overlay) variableIndex(name string) int {
+	for i, v := range o.vars {
+		if strings.EqualFold(v.name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// AnalyzeStatement is like the package-level AnalyzeStatement, with the
+// overlay's variables in scope. Wildcard tables match overlay tables too,
+// and not the base tables the script dropped.
+func (o *Overlay) AnalyzeStatement(sql string) (*AnalyzeOutput, error) {
+	lookup, err := o.catalog()
+	if err != nil {
+		return nil, err
+	}
+	tables := make(map[string][]ColumnDef, len(o.tables)+len(o.dropped))
+	for key := range o.dropped {
+		tables[key] = nil
+	}
+	for _, t := range o.tables {
+		tables[strings.ToLower(t.name)] = t.columns
+	}
+	out, err := o.base.analyze(sql, lookup, tables)
+	if err != nil {
+		return nil, err
+	}
+	return newAnalyzeOutput(out), nil
+}
+
+// AnalyzeExpression analyzes a standalone expression, such as a DEFAULT
+// value or an IF condition, and returns its type name.
+func (o *Overlay) AnalyzeExpression(sql string) (string, error) {
+	lookup, err := o.catalog()
+	if err != nil {
+		return "", err
+	}
+	typeName, err := bridge.AnalyzeExpression(sql, lookup, o.base.opts, "")
+	return typeName, newError(err)
+}
+
+// CheckAssignment reports an error unless the expression sql can be
+// assigned to a variable of type typeName, allowing implicit coercion.
+func (o *Overlay) CheckAssignment(sql, typeName string) error {
+	lookup, err := o.catalog()
+	if err != nil {
+		return err
+	}
+	_, err = bridge.AnalyzeExpression(sql, lookup, o.base.opts, typeName)
+	return newError(err)
+}
+
+// catalog returns the lookup catalog, rebuilding it if the overlay changed.
+func (o *Overlay) catalog() (bridge.Catalog, error) {
+	if !o.dirty {
+		return o.lookup, nil
+	}
+	o.release()
+
+	// The lookup catalog is created first so that routines are analyzed
+	// against everything before them, overlay and base alike; ZetaSQL
+	// looks names up in inner as it is filled.
+	inner := bridge.NewSimpleCatalog("script", o.base.factory)
+	lookup, err := bridge.NewMultiCatalog("script", inner, o.base.catalog())
+	if err != nil {
+		inner.Close()
+		return nil, err
+	}
+	o.inner = inner
+	o.lookup = lookup
+
+	for _, v := range o.vars {
+		if v.typeName == "" {
+			continue
+		}
+		if err := inner.AddConstant(v.name, v.typeName, o.base.opts); err != nil {
+			o.release()
+			return nil, err
+		}
+	}
+	for _, t := range o.tables {
+		if err := addTable(inner, o.base.opts, t.name, t.columns); err != nil {
+			o.release()
+			return nil, err
+		}
+	}
+	o.procedures = make(map[string]*Procedure)
+	o.functions = nil
+	o.dirty = false
+	var kept []overlayRoutine
+	for _, r := range o.routines {
+		if _, err := o.addRoutine(r.sql); err != nil {
+			o.broken = append(o.broken, &RoutineError{Name: r.name, Err: err})
+			continue
+		}
+		kept = append(kept, r)
+	}
+	o.routines = kept
+	if o.dirty {
+		// A dropped routine was registered under some of its paths; build
+		// again without it.
+		return o.catalog()
+	}
+	return lookup, nil
+}
+
+// addRoutine analyzes a routine of the script against the lookup catalog,
+// registers it with inner and returns its name.
+func (o *Overlay) addRoutine(sql string) (string, error) {
+	r, out, err := addRoutine(o.inner, o.lookup, o.base.inner, o.base.opts, sql)
+	if r != nil {
+		o.built = append(o.built, r)
+	}
+	if err != nil {
+		if r != nil {
+			o.dirty = true // it may be registered under some of its paths
+		}
+		return "", err
+	}
+	if p := newProcedure(out); p != nil {
+		o.procedures[strings.ToLower(p.Name)] = p
+		return p.Name, nil
+	}
+	name, _ := functionName(out)
+	o.functions = append(o.functions, name)
+	return name, nil
+}
+
+func (o *Overlay) release() {
+	if o.lookup != nil {
+		o.lookup.Close()
+		o.lookup = nil
+	}
+	if o.inner != nil {
+		o.inner.Close()
+		o.inner = nil
+	}
+	for _, r := range o.built {
+		r.Close()
+	}
+	o.built = nil
+}
+
+// Close releases the overlay. The base catalog is left untouched.
+func (o *Overlay) Close() {
+	o.release()
+}
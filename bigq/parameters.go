@@ -0,0 +1,97 @@
+package bigq
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pacer/go-bigq/internal/bridge"
+)
+
+// Parameter is a query parameter: a named one (@name) or, with an empty
+// Name, a positional one (?), numbered from 1 by Position.
+type Parameter struct {
+	Name     string
+	Position int
+	TypeName string
+}
+
+// WithUndeclaredParameters lets statements use query parameters that were
+// not added with AddParameter or AddPositionalParameter. Their types are
+// inferred from how they are used and reported in
+// AnalyzeOutput.UndeclaredParameters; a parameter used in ways that need
+// different types is an error.
+func WithUndeclaredParameters() CatalogOption {
+	return func(c *catalogConfig) {
+		c.undeclaredParameters = true
+	}
+}
+
+// AddParameter declares the named query parameter @name, written with or
+// without its @, for the statements analyzed against c. A statement can
+// use either named or positional parameters, so a catalog can declare only
+// one kind.
+func (c *Catalog) AddParameter(name, typeName string) error {
+	name = strings.TrimPrefix(name, "@")
+	if c.positionalParameters > 0 {
+		return fmt.Errorf("parameter @%s: positional parameters are already declared", name)
+	}
+	if err := c.opts.AddQueryParameter(name, typeName, c.inner); err != nil {
+		return err
+	}
+	c.namedParameters++
+	return nil
+}
+
+// AddPositionalParameter declares the next positional (?) query parameter,
+// as AddParameter does a named one.
+func (c *Catalog) AddPositionalParameter(typeName string) error {
+	if c.namedParameters > 0 {
+		return fmt.Errorf("positional parameter %d: named parameters are already declared", c.positionalParameters+1)
+	}
+	if err := c.opts.AddPositionalQueryParameter(typeName, c.inner); err != nil {
+		return err
+	}
+	c.opts.SetPositionalParameters(true)
+	c.positionalParameters++
+	return nil
+}
+
+// inferParameterMode reports whether the parameter mode of a statement
+// against c follows from the statement itself: whether undeclared
+// parameters are allowed and none are declared to decide it.
+func (c *Catalog) inferParameterMode() bool {
+	return c.undeclaredParameters && c.namedParameters == 0 && c.positionalParameters == 0
+}
+
+// positional reports whether the statement with parse tree tree is
+// analyzed with positional (?) rather than named (@name) parameters.
+// Declared parameters decide; otherwise the statement uses ? parameters if
+// it has any. tree is nil if the statement cannot have any.
+func (c *Catalog) positional(tree *Node) bool {
+	if !c.inferParameterMode() {
+		return c.positionalParameters > 0
+	}
+	if tree == nil {
+		return false
+	}
+	for _, n := range tree.Find("ParameterExpr") {
+		if len(n.Children) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func fromBridgeParameters(params []bridge.NameAndType) []Parameter {
+	var out []Parameter
+	position := 0
+	for _, p := range params {
+		param := Parameter{Name: p.Name, TypeName: p.TypeName}
+		if p.Name == "" {
+			position++
+			param.Position = position
+		}
+		out = append(out, param)
+	}
+	return out
+}
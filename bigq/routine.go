@@ -0,0 +1,131 @@
+package bigq
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pacer/go-bigq/internal/bridge"
+)
+
+// AddRoutine adds the function defined by a CREATE FUNCTION or CREATE
+// TABLE FUNCTION statement, or the procedure defined by a CREATE PROCEDURE
+// statement, so that later statements can call it. SQL and JavaScript UDFs
+// are supported, including templated ones with ANY TYPE arguments, as are
+// SQL table functions, whose calls in FROM are checked against their
+// argument types and produce the columns of their query (or of their
+// RETURNS TABLE clause). Function bodies are type-checked against the
+// catalog; a procedure's body is a script, left to the caller to check. A
+// qualified name such as dataset.my_udf resolves in the same path forms as
+// a table name.
+func (c *Catalog) AddRoutine(sql string) error {
+	r, out, err := addRoutine(c.inner, c.catalog(), c.inner, c.opts, sql)
+	if r != nil {
+		c.routines = append(c.routines, r)
+	}
+	if err != nil {
+		return err
+	}
+	if p := newProcedure(out); p != nil {
+		c.procedures[strings.ToLower(p.Name)] = p
+	}
+	if name, ok := functionName(out); ok {
+		c.functions = append(c.functions, name)
+	}
+	return nil
+}
+
+// FunctionNames returns the names functions can be called by: those of
+// the builtin functions and table functions, lower-cased, followed by
+// those added with AddRoutine, as they were defined.
+func (c *Catalog) FunctionNames() []string {
+	return append(c.BuiltinFunctionNames(), c.functions...)
+}
+
+// BuiltinFunctionNames returns the names of the builtin functions and
+// table functions, lower-cased.
+func (c *Catalog) BuiltinFunctionNames() []string {
+	var names []string
+	for _, name := range c.inner.FunctionNames() {
+		if strings.HasPrefix(name, "$") { // operators, such as $add
+			continue
+		}
+		// Functions added with AddRoutine are in the same catalog.
+		if slices.ContainsFunc(c.functions, func(f string) bool { return strings.EqualFold(f, name) }) {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// functionName returns the name of the function a CREATE [TABLE] FUNCTION
+// statement defines.
+func functionName(out *bridge.AnalyzeOutput) (string, bool) {
+	switch out.StatementKind {
+	case "CreateFunctionStmt", "CreateTableFunctionStmt":
+		return strings.Join(out.NamePath, "."), true
+	}
+	return "", false
+}
+
+// Procedure describes a procedure added with AddRoutine.
+type Procedure struct {
+	Name      string
+	Arguments []Argument
+	Body      string // the BEGIN ... END block
+}
+
+// newProcedure returns the procedure a CREATE PROCEDURE statement defines,
+// or nil for other statements.
+func newProcedure(out *bridge.AnalyzeOutput) *Procedure {
+	if out.StatementKind != "CreateProcedureStmt" {
+		return nil
+	}
+	p := &Procedure{Name: strings.Join(out.NamePath, "."), Body: out.Body}
+	for _, a := range out.Arguments {
+		p.Arguments = append(p.Arguments, Argument(a))
+	}
+	return p
+}
+
+// Procedure returns a procedure added with AddRoutine. Names are
+// case-insensitive and resolved against the default dataset and project
+// like names in SQL.
+func (c *Catalog) Procedure(name string) (*Procedure, bool) {
+	key, ok := c.resolve(name, func(key string) bool {
+		_, ok := c.procedures[key]
+		return ok
+	})
+	if !ok {
+		return nil, false
+	}
+	return c.procedures[key], true
+}
+
+// addRoutine analyzes a CREATE FUNCTION, TABLE FUNCTION or PROCEDURE
+// statement against lookup and registers the routine with cat. A function
+// may not take the name of one in builtins, the catalog holding the
+// builtin functions, which cat checks itself if it is that catalog. The
+// caller owns the returned routine, which is non-nil even on failure if
+// cat may refer to it, and must close it after cat.
+func addRoutine(cat *bridge.SimpleCatalog, lookup bridge.Catalog, builtins *bridge.SimpleCatalog, opts *bridge.AnalyzerOptions, sql string) (*bridge.Routine, *bridge.AnalyzeOutput, error) {
+	r, out, err := bridge.NewRoutine(sql, lookup, opts)
+	if err != nil {
+		return nil, nil, newError(err)
+	}
+	name := strings.Join(out.NamePath, ".")
+	if out.StatementKind == "CreateFunctionStmt" && builtins != cat && builtins.HasFunction(name) {
+		r.Close()
+		return nil, nil, fmt.Errorf("function %s already exists", name)
+	}
+	err = registerPaths(cat, name, func(sub *bridge.SimpleCatalog, leaf string, _ bool) error {
+		return sub.AddRoutine(leaf, r)
+	})
+	if err != nil {
+		// Aliases registered before the failure still point at r, so it
+		// cannot be freed before cat.
+		return r, nil, err
+	}
+	return r, out, nil
+}
@@ -0,0 +1,105 @@
+package bigq
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/pacer/go-bigq/internal/bridge"
+)
+
+// ScriptStatement is one top-level statement of a script as seen by the
+// parser.
+type ScriptStatement struct {
+	Start int   // byte offset of the statement in the script
+	End   int   // byte offset one past the statement
+	Node  *Node // parse tree of the statement, nil if it failed to parse
+	Err   error // syntax error, an *Error; nil if the statement parsed
+
+	// ErrOffset is the byte offset of the syntax error in the script, or
+	// -1 if the parser reported no location.
+	ErrOffset int
+}
+
+// ParseScriptStatements parses a script statement by statement. Unlike
+// ParseScript it does not stop at the first syntax error: the failing
+// statement is reported, the parser skips to the next statement boundary
+// and carries on, so every broken statement is returned.
+func ParseScriptStatements(sql string) []ScriptStatement {
+	if script, err := ParseScriptAST(sql); err == nil {
+		var stmts []ScriptStatement
+		for _, n := range script.Statements() {
+			stmts = append(stmts, ScriptStatement{Start: n.Start, End: n.End, Node: n, ErrOffset: -1})
+		}
+		return stmts
+	}
+
+	p := bridge.NewScriptParser(sql)
+	defer p.Close()
+
+	var stmts []ScriptStatement
+	pos := 0
+	for pos < len(sql) && !onlyTrivia(sql[pos:]) {
+		nodes, next, atEnd, err := p.Next(pos)
+		if err == nil {
+			root := buildTree(nodes)
+			stmts = append(stmts, ScriptStatement{Start: root.Start, End: root.End, Node: root, ErrOffset: -1})
+			if atEnd || next <= pos {
+				break
+			}
+			pos = next
+			continue
+		}
+
+		stmt := ScriptStatement{Start: pos, Err: newError(err), ErrOffset: -1}
+		var status bridge.Status
+		if errors.As(err, &status) {
+			stmt.ErrOffset = status.ErrorOffset
+		}
+
+		// Resume after the statement containing the error, blocks and
+		// all, so that the rest of a broken BEGIN or IF block is not
+		// parsed as statements of its own. If the block's end cannot be
+		// found, skip from the error location instead, which at least
+		// avoids reporting the same mistake again.
+		next = p.SkipStatement(pos)
+		if next < 0 {
+			from := pos
+			if stmt.ErrOffset > pos {
+				from = stmt.ErrOffset
+			}
+			next = p.Skip(from)
+		}
+		if next <= pos {
+			next = len(sql)
+		}
+		stmt.End = next
+		stmts = append(stmts, stmt)
+		pos = next
+	}
+	return stmts
+}
+
+// onlyTrivia reports whether s holds nothing but whitespace and comments.
+func onlyTrivia(s string) bool {
+	for {
+		s = strings.TrimLeft(s, " \t\r\n")
+		switch {
+		case s == "":
+			return true
+		case strings.HasPrefix(s, "--"), strings.HasPrefix(s, "#"):
+			i := strings.IndexByte(s, '\n')
+			if i < 0 {
+				return true
+			}
+			s = s[i+1:]
+		case strings.HasPrefix(s, "/*"):
+			i := strings.Index(s[2:], "*/")
+			if i < 0 {
+				return false
+			}
+			s = s[i+4:]
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package bigq
+
+import (
+	"github.com/pacer/go-bigq/internal/bridge"
+)
+
+// systemVariables are BigQuery's system variables, keyed by name without
+// the @@. The @@error variables, which BigQuery sets only inside an
+// EXCEPTION WHEN ERROR THEN handler, are declared everywhere: a single
+// statement can't tell whether it is in one, so the linter checks that.
+var systemVariables = map[string]string{
+	"current_job_id":              "STRING",
+	"dataset_id":                  "STRING",
+	"dataset_project_id":          "STRING",
+	"last_job_id":                 "STRING",
+	"location":                    "STRING",
+	"project_id":                  "STRING",
+	"query_label":                 "STRING",
+	"reservation":                 "STRING",
+	"row_count":                   "INT64",
+	"session_id":                  "STRING",
+	"time_zone":                   "STRING",
+	"script.bytes_billed":         "INT64",
+	"script.bytes_processed":      "INT64",
+	"script.creation_time":        "TIMESTAMP",
+	"script.job_id":               "STRING",
+	"script.num_child_jobs":       "INT64",
+	"script.slot_ms":              "INT64",
+	"error.message":               "STRING",
+	"error.statement_text":        "STRING",
+	"error.formatted_stack_trace": "STRING",
+	"error.stack_trace":           "ARRAY<STRUCT<line INT64, column INT64, filename STRING, location STRING>>",
+}
+
+// addSystemVariables declares the system variables in opts.
+func addSystemVariables(opts *bridge.AnalyzerOptions, cat *bridge.SimpleCatalog) error {
+	for name, typeName := range systemVariables {
+		if err := opts.AddSystemVariable(name, typeName, cat); err != nil {
+			return err
+		}
+	}
+	return nil
+}
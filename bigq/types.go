@@ -0,0 +1,41 @@
+package bigq
+
+import (
+	"sync"
+
+	"github.com/pacer/go-bigq/internal/bridge"
+)
+
+// typeEnv is the catalog and options ParseType resolves types with. Types
+// need no tables or functions, so one empty catalog serves every call.
+type typeEnv struct {
+	catalog *bridge.SimpleCatalog
+	opts    *bridge.AnalyzerOptions
+}
+
+var defaultTypeEnv = sync.OnceValue(func() *typeEnv {
+	langOpts := bridge.NewLanguageOptions()
+	langOpts.EnableMaximumLanguageFeatures()
+	langOpts.SetProductMode(bridge.ProductModeExternal)
+	langOpts.SetSupportsAllStatementKinds()
+
+	opts := bridge.NewAnalyzerOptions()
+	opts.SetLanguageOptions(langOpts)
+	return &typeEnv{
+		catalog: bridge.NewSimpleCatalog("types", bridge.NewTypeFactory()),
+		opts:    opts,
+	}
+})
+
+// ParseType validates a BigQuery type string as it would appear in a column
+// definition and returns its canonical spelling, e.g. "numeric(10,2)"
+// becomes "NUMERIC(10, 2)". Parameterized types, RANGE<...>, quoted STRUCT
+// field names and NOT NULL fields are accepted; NOT NULL is not part of the
+// returned type. Anything else a column definition may have, such as
+// DEFAULT, OPTIONS or COLLATE, is an error. On failure the error is an
+// *Error whose location is relative to typeName.
+func ParseType(typeName string) (string, error) {
+	env := defaultTypeEnv()
+	normalized, err := bridge.ParseType(typeName, env.catalog, env.opts)
+	return normalized, newError(err)
+}
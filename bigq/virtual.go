@@ -0,0 +1,96 @@
+package bigq
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/pacer/go-bigq/internal/bridge"
+)
+
+// analyze analyzes sql against base. Tables that exist only by the way they
+// are named, wildcard tables such as `dataset.events_*` and (if enabled)
+// INFORMATION_SCHEMA views, are added for the duration of the analysis.
+// Wildcard tables are built from the tables of c and extra (keyed by
+// lower-cased name, nil for a dropped table of c) that they match; views
+// are built with c and only aliased under the names sql queries them by.
+//
+// sql is parsed once: the parse tree that tells which tables are virtual
+// and which kind of parameters sql uses is the one analyzed.
+func (c *Catalog) analyze(sql string, base bridge.Catalog, extra map[string][]ColumnDef) (*bridge.AnalyzeOutput, error) {
+	parsed, err := bridge.ParseForAnalysis(sql, c.opts, c.needsTree(sql))
+	if err != nil {
+		return nil, newError(err)
+	}
+	defer parsed.Close()
+	tree := buildTree(parsed.Nodes)
+	positional := c.positional(tree)
+
+	names := virtualTableNames(tree)
+	if len(names) == 0 {
+		out, err := parsed.Analyze(base, c.opts, positional)
+		return out, newError(err)
+	}
+
+	virtual := bridge.NewSimpleCatalog("virtual", c.factory)
+	defer virtual.Close()
+	for _, name := range names {
+		if table, ok := c.informationSchemaTable(name); ok {
+			aliasTable(virtual, name, table)
+			continue
+		}
+		columns, ok := c.wildcardColumns(name, extra)
+		if !ok {
+			continue // the analyzer reports it as not found
+		}
+		if err := addTable(virtual, c.opts, name, columns); err != nil {
+			return nil, err
+		}
+	}
+	lookup, err := bridge.NewMultiCatalog("virtual", base, virtual)
+	if err != nil {
+		return nil, err
+	}
+	defer lookup.Close()
+	out, err := parsed.Analyze(lookup, c.opts, positional)
+	return out, newError(err)
+}
+
+// aliasTable adds table to cat under name, in every way the dotted name can
+// be split into path components, as addTable does.
+func aliasTable(cat *bridge.SimpleCatalog, name string, table *bridge.Table) {
+	registerPaths(cat, name, func(sub *bridge.SimpleCatalog, leaf string, _ bool) error {
+		sub.AddTableAlias(leaf, table)
+		return nil
+	})
+}
+
+// needsTree reports whether analyzing sql needs its parse tree: whether it
+// may read a virtual table or, if its parameter mode is to be inferred,
+// have a ? parameter. Most statements do neither, and skip building it.
+func (c *Catalog) needsTree(sql string) bool {
+	return strings.Contains(sql, "*") ||
+		strings.Contains(strings.ToUpper(sql), informationSchema) ||
+		c.inferParameterMode() && strings.Contains(sql, "?")
+}
+
+// virtualTableNames returns the distinct names, as written, of the tables
+// the statement with parse tree tree reads that end in * or have an
+// INFORMATION_SCHEMA component. tree may be nil.
+func virtualTableNames(tree *Node) []string {
+	if tree == nil {
+		return nil
+	}
+	var names []string
+	for _, n := range tree.Find("TablePathExpression") {
+		for _, c := range n.Children {
+			name := strings.Join(c.Path(), ".")
+			if name == "" || slices.Contains(names, name) {
+				continue
+			}
+			if strings.HasSuffix(name, "*") || slices.ContainsFunc(strings.Split(name, "."), isInformationSchema) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
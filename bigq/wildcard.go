@@ -0,0 +1,59 @@
+package bigq
+
+import (
+	"slices"
+	"strings"
+)
+
+// wildcardColumns returns the columns of a wildcard table: the union of
+// the columns of the tables in the same dataset whose names start with the
+// part before the *, taken in name order so that the first table to have
+// a column decides its type, followed by the _TABLE_SUFFIX pseudo-column.
+// Like other names, the prefix is also tried under the default dataset
+// and project. A nil entry in extra hides the table of c of that name.
+func (c *Catalog) wildcardColumns(name string, extra map[string][]ColumnDef) ([]ColumnDef, bool) {
+	if !strings.HasSuffix(name, "*") {
+		return nil, false
+	}
+	prefix := strings.ToLower(strings.TrimSuffix(name, "*"))
+	candidates := []string{prefix}
+	for _, d := range c.defaults {
+		candidates = append(candidates, strings.ToLower(d)+"."+prefix)
+	}
+
+	for _, p := range candidates {
+		var matches []string
+		for _, tables := range []map[string][]ColumnDef{extra, c.tables} {
+			for key := range tables {
+				if columns, ok := extra[key]; ok && columns == nil {
+					continue
+				}
+				if strings.HasPrefix(key, p) && !strings.Contains(key[len(p):], ".") && !slices.Contains(matches, key) {
+					matches = append(matches, key)
+				}
+			}
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		slices.Sort(matches)
+
+		var columns []ColumnDef
+		for _, key := range matches {
+			table, ok := extra[key]
+			if !ok {
+				table = c.tables[key]
+			}
+			for _, col := range table {
+				if col.Pseudo || slices.ContainsFunc(columns, func(x ColumnDef) bool {
+					return strings.EqualFold(x.Name, col.Name)
+				}) {
+					continue
+				}
+				columns = append(columns, col)
+			}
+		}
+		return append(columns, ColumnDef{Name: "_TABLE_SUFFIX", TypeName: "STRING", Pseudo: true}), true
+	}
+	return nil, false
+}
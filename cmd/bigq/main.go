@@ -6,10 +6,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/pacer/go-bigq/bigq"
 	"github.com/pacer/go-bigq/internal/catalog"
 	"github.com/pacer/go-bigq/internal/lint"
+	"github.com/pacer/go-bigq/internal/schema"
 )
 
 var version = "dev"
@@ -41,34 +43,35 @@ func runLint(args []string, stdout, stderr io.Writer) int {
 	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
 	fs.SetOutput(stderr)
 
-	schemaPath := fs.String("schema", "", "Path to schema JSON file")
-	schemaDir := fs.String("schema-dir", "", "Directory of schema JSON files")
+	var cf catalogFlags
+	fs.StringVar(&cf.schemaPath, "schema", "", "Path to schema JSON file")
+	fs.StringVar(&cf.schemaDir, "schema-dir", "", "Directory of schema JSON files")
+	fs.StringVar(&cf.schemaDDL, "schema-ddl", "", "CREATE TABLE/VIEW DDL file or directory of .sql files")
 	format := fs.String("format", "text", "Output format: text, json, github-actions")
 	useStdin := fs.Bool("stdin", false, "Read SQL from stdin")
+	fs.StringVar(&cf.defaultProject, "default-project", "", "Project for table names without one")
+	fs.StringVar(&cf.defaultDataset, "default-dataset", "", "Dataset (or project.dataset) for unqualified table names")
+	fs.BoolVar(&cf.informationSchema, "information-schema", false, "Resolve INFORMATION_SCHEMA views")
+	fs.Var(&cf.params, "param", "Query parameter as name:TYPE, or :TYPE for the next positional one (repeatable)")
+	fs.BoolVar(&cf.inferParams, "infer-params", false, "Allow undeclared query parameters and report their inferred types")
 
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
 
 	// Build catalog from schema
-	var cat *bigq.Catalog
-	if *schemaPath != "" {
-		var err error
-		cat, err = catalog.BuildFromFile(*schemaPath)
-		if err != nil {
-			fmt.Fprintf(stderr, "Error loading schema: %s\n", err)
-			return 2
-		}
-		defer cat.Close()
-	} else if *schemaDir != "" {
-		var err error
-		cat, err = catalog.BuildFromDir(*schemaDir)
-		if err != nil {
-			fmt.Fprintf(stderr, "Error loading schema directory: %s\n", err)
-			return 2
-		}
+	cat, err := cf.build()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error loading %s\n", err)
+		return 2
+	}
+	if cat != nil {
 		defer cat.Close()
 	}
+	if err := cf.params.declare(cat); err != nil {
+		fmt.Fprintf(stderr, "Error: %s\n", err)
+		return 2
+	}
 
 	linter := lint.New(cat)
 	var allResults []lint.Result
@@ -109,8 +112,8 @@ func runLint(args []string, stdout, stderr io.Writer) int {
 		enc.Encode(allResults)
 	case "github-actions":
 		for _, r := range allResults {
-			fmt.Fprintf(stdout, "::error file=%s,line=%d,col=%d::%s\n",
-				r.File, r.Line, r.Column, r.Message)
+			fmt.Fprintf(stdout, "::%s file=%s,line=%d,col=%d,endLine=%d,endColumn=%d,title=%s::%s\n",
+				r.Level, r.File, r.Line, r.Column, r.EndLine, r.EndColumn, r.Code, r.Detail())
 		}
 	default: // text
 		for _, r := range allResults {
@@ -118,8 +121,135 @@ func runLint(args []string, stdout, stderr io.Writer) int {
 		}
 	}
 
-	if len(allResults) > 0 {
-		return 1
+	// Warnings are reported but don't fail the run.
+	for _, r := range allResults {
+		if r.Level == lint.LevelError {
+			return 1
+		}
 	}
 	return 0
 }
+
+// catalogFlags are the lint flags that describe the catalog.
+type catalogFlags struct {
+	schemaPath, schemaDir, schemaDDL string
+
+	defaultProject, defaultDataset string
+	informationSchema              bool
+	params                         paramFlags
+	inferParams                    bool
+}
+
+// options returns the catalog options the flags ask for.
+func (f *catalogFlags) options() []bigq.CatalogOption {
+	var opts []bigq.CatalogOption
+	if f.defaultProject != "" {
+		opts = append(opts, bigq.WithDefaultProject(f.defaultProject))
+	}
+	if f.defaultDataset != "" {
+		opts = append(opts, bigq.WithDefaultDataset(f.defaultDataset))
+	}
+	if f.informationSchema {
+		opts = append(opts, bigq.WithInformationSchema())
+	}
+	if f.inferParams {
+		opts = append(opts, bigq.WithUndeclaredParameters())
+	}
+	return opts
+}
+
+// standalone reports whether the flags ask for a catalog of their own,
+// for INFORMATION_SCHEMA views or query parameters, even without a schema
+// source.
+func (f *catalogFlags) standalone() bool {
+	return f.informationSchema || f.inferParams || len(f.params) > 0
+}
+
+// build loads every given schema source into one catalog. It returns a
+// nil catalog if no source is given and the flags need none otherwise.
+func (f *catalogFlags) build() (*bigq.Catalog, error) {
+	if f.schemaPath == "" && f.schemaDir == "" && f.schemaDDL == "" && !f.standalone() {
+		return nil, nil
+	}
+	cat, err := bigq.NewCatalog("root", f.options()...)
+	if err != nil {
+		return nil, fmt.Errorf("catalog: %w", err)
+	}
+	if err := loadSchemas(cat, f.schemaPath, f.schemaDir, f.schemaDDL); err != nil {
+		cat.Close()
+		return nil, err
+	}
+	return cat, nil
+}
+
+func loadSchemas(cat *bigq.Catalog, schemaPath, schemaDir, schemaDDL string) error {
+	if schemaPath != "" {
+		s, err := schema.LoadFile(schemaPath)
+		if err == nil {
+			err = catalog.AddSchema(cat, s, checkProcedure)
+		}
+		if err != nil {
+			return fmt.Errorf("schema: %w", err)
+		}
+	}
+	if schemaDir != "" {
+		s, err := schema.LoadDir(schemaDir)
+		if err == nil {
+			err = catalog.AddSchema(cat, s, checkProcedure)
+		}
+		if err != nil {
+			return fmt.Errorf("schema directory: %w", err)
+		}
+	}
+	if schemaDDL != "" {
+		if err := catalog.AddDDL(cat, schemaDDL, checkProcedure); err != nil {
+			return fmt.Errorf("schema DDL: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkProcedure lints a CREATE PROCEDURE statement from a schema source,
+// body included, and returns the errors found.
+func checkProcedure(cat *bigq.Catalog, sql string) []catalog.ProcedureError {
+	var errs []catalog.ProcedureError
+	for _, r := range lint.New(cat).LintSQL(sql) {
+		if r.Level == lint.LevelError {
+			errs = append(errs, catalog.ProcedureError{Offset: r.Offset, Message: r.Message})
+		}
+	}
+	return errs
+}
+
+// paramFlags collects --param flags.
+type paramFlags []string
+
+func (p *paramFlags) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *paramFlags) Set(value string) error {
+	if !strings.Contains(value, ":") {
+		return fmt.Errorf("want name:TYPE or :TYPE, got %q", value)
+	}
+	*p = append(*p, value)
+	return nil
+}
+
+// declare adds the parameters to cat, in order. An empty name declares
+// the next positional parameter.
+func (p paramFlags) declare(cat *bigq.Catalog) error {
+	for _, param := range p {
+		name, typeName, _ := strings.Cut(param, ":")
+		var err error
+		if name == "" {
+			err = cat.AddPositionalParameter(typeName)
+		} else {
+			err = cat.AddParameter(name, typeName)
+		}
+		if err != nil {
+			return fmt.Errorf("--param %s: %w", param, err)
+		}
+	}
+	return nil
+}
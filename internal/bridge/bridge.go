@@ -10,6 +10,7 @@ import "C"
 import (
 	"fmt"
 	"runtime"
+	"strings"
 	"unsafe"
 )
 
@@ -19,13 +20,32 @@ type Status struct {
 	ErrorMessage string
 	ErrorLine    int // 1-based, 0 if not available
 	ErrorColumn  int // 1-based, 0 if not available
+	ErrorOffset  int // 0-based byte offset into the input, -1 if not available
+	Code         int // absl status code, e.g. 3 for INVALID_ARGUMENT; 0 if OK
+	Source       int // what the error is about, one of the Error* sources
 }
 
+// Status sources, mirroring the ZETASQL_ERROR_* defines in zetasql_bridge.h.
+const (
+	ErrorOther             = C.ZETASQL_ERROR_OTHER
+	ErrorSyntax            = C.ZETASQL_ERROR_SYNTAX
+	ErrorUnknownTable      = C.ZETASQL_ERROR_UNKNOWN_TABLE
+	ErrorUnknownColumn     = C.ZETASQL_ERROR_UNKNOWN_COLUMN
+	ErrorUnknownFunction   = C.ZETASQL_ERROR_UNKNOWN_FUNCTION
+	ErrorUnknownType       = C.ZETASQL_ERROR_UNKNOWN_TYPE
+	ErrorUnknownParameter  = C.ZETASQL_ERROR_UNKNOWN_PARAMETER
+	ErrorTypeMismatch      = C.ZETASQL_ERROR_TYPE_MISMATCH
+	ErrorSignatureMismatch = C.ZETASQL_ERROR_SIGNATURE_MISMATCH
+)
+
 func statusFromC(s C.zetasql_Status) Status {
 	st := Status{
 		OK:          bool(s.ok),
 		ErrorLine:   int(s.error_line),
 		ErrorColumn: int(s.error_column),
+		ErrorOffset: int(s.error_offset),
+		Code:        int(s.error_code),
+		Source:      int(s.error_source),
 	}
 	if s.error_message != nil {
 		st.ErrorMessage = C.GoString(s.error_message)
@@ -34,13 +54,12 @@ func statusFromC(s C.zetasql_Status) Status {
 	return st
 }
 
+// Error returns the message. The position is left to callers, who know
+// what text it is relative to.
 func (s Status) Error() string {
 	if s.OK {
 		return ""
 	}
-	if s.ErrorLine > 0 {
-		return fmt.Sprintf("%d:%d: %s", s.ErrorLine, s.ErrorColumn, s.ErrorMessage)
-	}
 	return s.ErrorMessage
 }
 
@@ -98,10 +117,21 @@ func (lo *LanguageOptions) SetSupportsAllStatementKinds() {
 	C.zetasql_LanguageOptions_SetSupportsAllStatementKinds(lo.raw)
 }
 
+// Catalog is a catalog statements can be analyzed against.
+type Catalog interface {
+	// catalogHandle returns the googlesql::Catalog* for the catalog.
+	catalogHandle() unsafe.Pointer
+	typeFactory() *TypeFactory
+}
+
 // SimpleCatalog holds schema information for SQL analysis.
 type SimpleCatalog struct {
 	raw     unsafe.Pointer
 	factory *TypeFactory // kept alive for the catalog's lifetime
+
+	// subs holds the sub-catalogs created by AddSubCatalog, keyed by
+	// lower-cased name, since ZetaSQL rejects duplicate catalog names.
+	subs map[string]*SimpleCatalog
 }
 
 func NewSimpleCatalog(name string, factory *TypeFactory) *SimpleCatalog {
@@ -122,42 +152,90 @@ func (c *SimpleCatalog) Close() {
 	}
 }
 
+func (c *SimpleCatalog) catalogHandle() unsafe.Pointer {
+	return C.zetasql_SimpleCatalog_AsCatalog(c.raw)
+}
+
+func (c *SimpleCatalog) typeFactory() *TypeFactory { return c.factory }
+
+// FunctionNames returns the names of the functions and table-valued
+// functions added to c itself, including the builtin ones, lower-cased.
+// Those of its sub-catalogs are not included.
+func (c *SimpleCatalog) FunctionNames() []string {
+	var names **C.char
+	var count C.int
+	C.zetasql_SimpleCatalog_FunctionNames(c.raw, &names, &count)
+	defer C.zetasql_free_strings(names, count)
+	return stringsFromC(names, count)
+}
+
 func (c *SimpleCatalog) AddBuiltinFunctionsAndTypes(langOpts *LanguageOptions) error {
 	var st C.zetasql_Status
 	C.zetasql_SimpleCatalog_AddBuiltinFunctionsAndTypes(c.raw, langOpts.raw, &st)
 	status := statusFromC(st)
 	if !status.OK {
-		return fmt.Errorf("AddBuiltinFunctionsAndTypes: %s", status.Error())
+		return fmt.Errorf("AddBuiltinFunctionsAndTypes: %w", status)
 	}
 	return nil
 }
 
+// AddSubCatalog returns the named sub-catalog, creating it if needed. The
+// sub-catalog is owned by c. Names are case-insensitive.
 func (c *SimpleCatalog) AddSubCatalog(name string) *SimpleCatalog {
+	key := strings.ToLower(name)
+	if sub, ok := c.subs[key]; ok {
+		return sub
+	}
 	cname := C.CString(name)
 	defer C.free(unsafe.Pointer(cname))
-	sub := C.zetasql_SimpleCatalog_AddSubCatalog(c.raw, cname)
-	return &SimpleCatalog{raw: sub, factory: c.factory}
+	sub := &SimpleCatalog{
+		raw:     C.zetasql_SimpleCatalog_AddSubCatalog(c.raw, cname),
+		factory: c.factory,
+	}
+	if c.subs == nil {
+		c.subs = make(map[string]*SimpleCatalog)
+	}
+	c.subs[key] = sub
+	return sub
 }
 
 // ColumnDef defines a column for table creation.
 type ColumnDef struct {
 	Name     string
 	TypeName string // e.g. "INT64", "STRING", "ARRAY<STRING>"
+	Mode     string // "", "REQUIRED" or "REPEATED", which needs an ARRAY type
+
+	// Pseudo marks a pseudo-column such as _PARTITIONTIME, which can be
+	// selected by name but is not part of SELECT *.
+	Pseudo bool
+}
+
+// Table is a table created by NewTable. It belongs to the catalog it is
+// added to with AddTable and may be aliased elsewhere with AddTableAlias.
+type Table struct {
+	raw unsafe.Pointer
 }
 
-func (c *SimpleCatalog) AddTable(name string, columns []ColumnDef) error {
+// NewTable creates a table with the given columns. The name is the table's
+// full name as reported in analyzer output. Column types are resolved
+// against catalog with the language features enabled in opts.
+func NewTable(name string, columns []ColumnDef, catalog Catalog, opts *AnalyzerOptions) (*Table, error) {
 	cname := C.CString(name)
 	defer C.free(unsafe.Pointer(cname))
 
 	cColumns := make([]C.zetasql_ColumnDef, len(columns))
-	cStrings := make([]*C.char, len(columns)*2)
+	cStrings := make([]*C.char, len(columns)*3)
 	for i, col := range columns {
 		cn := C.CString(col.Name)
 		ct := C.CString(col.TypeName)
-		cStrings[i*2] = cn
-		cStrings[i*2+1] = ct
+		cm := C.CString(col.Mode)
+		cStrings[i*3] = cn
+		cStrings[i*3+1] = ct
+		cStrings[i*3+2] = cm
 		cColumns[i].name = cn
 		cColumns[i].type_name = ct
+		cColumns[i].mode = cm
+		cColumns[i].is_pseudo = C.bool(col.Pseudo)
 	}
 	defer func() {
 		for _, s := range cStrings {
@@ -171,16 +249,145 @@ func (c *SimpleCatalog) AddTable(name string, columns []ColumnDef) error {
 	}
 
 	var st C.zetasql_Status
-	tableRaw := C.zetasql_SimpleTable_new(cname, colPtr, C.int(len(columns)), c.factory.raw, &st)
+	raw := C.zetasql_SimpleTable_new(cname, colPtr, C.int(len(columns)),
+		catalog.catalogHandle(), catalog.typeFactory().raw, opts.raw, &st)
+	status := statusFromC(st)
+	if !status.OK {
+		return nil, fmt.Errorf("create table %s: %w", name, status)
+	}
+	return &Table{raw: raw}, nil
+}
+
+// AddTable adds t under name and takes ownership of it.
+func (c *SimpleCatalog) AddTable(name string, t *Table) {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	C.zetasql_SimpleCatalog_AddTable(c.raw, cname, t.raw, true)
+}
+
+// AddTableAlias adds t under name without taking ownership. The catalog
+// that owns t must live at least as long as c.
+func (c *SimpleCatalog) AddTableAlias(name string, t *Table) {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	C.zetasql_SimpleCatalog_AddTable(c.raw, cname, t.raw, false)
+}
+
+// Routine is a function, table function or procedure defined by a CREATE
+// statement. It must be closed after every catalog it was added to.
+type Routine struct {
+	raw unsafe.Pointer
+}
+
+// NewRoutine analyzes a CREATE FUNCTION, CREATE TABLE FUNCTION or CREATE
+// PROCEDURE statement against catalog and returns the routine it defines,
+// along with the statement's analysis as ParsedStatement.Analyze returns
+// it. SQL function bodies are type-checked; procedure bodies are not.
+func NewRoutine(sql string, catalog Catalog, opts *AnalyzerOptions) (*Routine, *AnalyzeOutput, error) {
+	csql := C.CString(sql)
+	defer C.free(unsafe.Pointer(csql))
+
+	var out C.zetasql_AnalyzerOutput
+	var st C.zetasql_Status
+	raw := C.zetasql_Routine_new(csql, catalog.catalogHandle(), catalog.typeFactory().raw, opts.raw, &out, &st)
+	status := statusFromC(st)
+	if !status.OK {
+		return nil, nil, fmt.Errorf("analysis error: %w", status)
+	}
+	return &Routine{raw: raw}, analyzeOutputFromC(&out), nil
+}
+
+func (r *Routine) Close() {
+	if r.raw != nil {
+		C.zetasql_Routine_free(r.raw)
+		r.raw = nil
+	}
+}
+
+// AddRoutine adds r under name without taking ownership. It fails if a
+// function of that name already exists, including a builtin. Table
+// functions and procedures have namespaces of their own.
+func (c *SimpleCatalog) AddRoutine(name string, r *Routine) error {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	var st C.zetasql_Status
+	C.zetasql_SimpleCatalog_AddRoutine(c.raw, cname, r.raw, &st)
 	status := statusFromC(st)
 	if !status.OK {
-		return fmt.Errorf("create table %s: %s", name, status.Error())
+		return fmt.Errorf("add routine %s: %w", name, status)
 	}
+	return nil
+}
+
+// HasFunction reports whether c itself, not counting its sub-catalogs, has
+// a function of the given name, builtin or not. Names are
+// case-insensitive.
+func (c *SimpleCatalog) HasFunction(name string) bool {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	return bool(C.zetasql_SimpleCatalog_HasFunction(c.raw, cname))
+}
 
-	C.zetasql_SimpleCatalog_AddTable(c.raw, tableRaw)
+// AddConstant adds a named constant of the given type. Script variables are
+// registered this way so that bare identifiers in statements resolve to them.
+func (c *SimpleCatalog) AddConstant(name, typeName string, opts *AnalyzerOptions) error {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	ctype := C.CString(typeName)
+	defer C.free(unsafe.Pointer(ctype))
+
+	var st C.zetasql_Status
+	C.zetasql_SimpleCatalog_AddConstant(c.raw, cname, ctype, opts.raw, &st)
+	status := statusFromC(st)
+	if !status.OK {
+		return fmt.Errorf("add constant %s: %w", name, status)
+	}
 	return nil
 }
 
+// MultiCatalog resolves names against a list of catalogs in order.
+type MultiCatalog struct {
+	raw      unsafe.Pointer
+	factory  *TypeFactory
+	catalogs []Catalog // kept alive for the MultiCatalog's lifetime
+}
+
+// NewMultiCatalog creates a catalog that looks names up in each of the
+// given catalogs in turn. Types are created with the first catalog's factory.
+func NewMultiCatalog(name string, catalogs ...Catalog) (*MultiCatalog, error) {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	handles := C.malloc(C.size_t(len(catalogs)) * C.size_t(unsafe.Sizeof(unsafe.Pointer(nil))))
+	defer C.free(handles)
+	list := unsafe.Slice((*unsafe.Pointer)(handles), len(catalogs))
+	for i, cat := range catalogs {
+		list[i] = cat.catalogHandle()
+	}
+
+	var st C.zetasql_Status
+	raw := C.zetasql_MultiCatalog_new(cname, (*unsafe.Pointer)(handles), C.int(len(catalogs)), &st)
+	status := statusFromC(st)
+	if !status.OK {
+		return nil, fmt.Errorf("create catalog %s: %w", name, status)
+	}
+	mc := &MultiCatalog{raw: raw, factory: catalogs[0].typeFactory(), catalogs: catalogs}
+	runtime.SetFinalizer(mc, func(m *MultiCatalog) { m.Close() })
+	return mc, nil
+}
+
+func (m *MultiCatalog) Close() {
+	if m.raw != nil {
+		C.zetasql_MultiCatalog_free(m.raw)
+		m.raw = nil
+	}
+}
+
+func (m *MultiCatalog) catalogHandle() unsafe.Pointer { return m.raw }
+
+func (m *MultiCatalog) typeFactory() *TypeFactory { return m.factory }
+
 // AnalyzerOptions controls analysis behavior.
 type AnalyzerOptions struct {
 	raw unsafe.Pointer
@@ -203,6 +410,69 @@ func (ao *AnalyzerOptions) SetLanguageOptions(langOpts *LanguageOptions) {
 	C.zetasql_AnalyzerOptions_SetLanguageOptions(ao.raw, langOpts.raw)
 }
 
+// AddQueryParameter declares the named parameter @name. Its type is
+// resolved against catalog, whose type factory must outlive ao.
+func (ao *AnalyzerOptions) AddQueryParameter(name, typeName string, catalog *SimpleCatalog) error {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	ctype := C.CString(typeName)
+	defer C.free(unsafe.Pointer(ctype))
+
+	var st C.zetasql_Status
+	C.zetasql_AnalyzerOptions_AddQueryParameter(ao.raw, cname, ctype, catalog.raw, &st)
+	status := statusFromC(st)
+	if !status.OK {
+		return fmt.Errorf("add parameter @%s: %w", name, status)
+	}
+	return nil
+}
+
+// AddPositionalQueryParameter declares the next positional (?) parameter,
+// as AddQueryParameter does a named one.
+func (ao *AnalyzerOptions) AddPositionalQueryParameter(typeName string, catalog *SimpleCatalog) error {
+	ctype := C.CString(typeName)
+	defer C.free(unsafe.Pointer(ctype))
+
+	var st C.zetasql_Status
+	C.zetasql_AnalyzerOptions_AddPositionalQueryParameter(ao.raw, ctype, catalog.raw, &st)
+	status := statusFromC(st)
+	if !status.OK {
+		return fmt.Errorf("add positional parameter: %w", status)
+	}
+	return nil
+}
+
+// AddSystemVariable declares the system variable @@name, where name is a
+// dotted path such as "script.job_id". Its type is resolved as for
+// AddQueryParameter.
+func (ao *AnalyzerOptions) AddSystemVariable(name, typeName string, catalog *SimpleCatalog) error {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	ctype := C.CString(typeName)
+	defer C.free(unsafe.Pointer(ctype))
+
+	var st C.zetasql_Status
+	C.zetasql_AnalyzerOptions_AddSystemVariable(ao.raw, cname, ctype, catalog.raw, &st)
+	status := statusFromC(st)
+	if !status.OK {
+		return fmt.Errorf("add system variable @@%s: %w", name, status)
+	}
+	return nil
+}
+
+// SetPositionalParameters selects positional (?) rather than named (@name)
+// parameters, the default. A statement can use only one kind.
+func (ao *AnalyzerOptions) SetPositionalParameters(positional bool) {
+	C.zetasql_AnalyzerOptions_SetPositionalParameters(ao.raw, C.bool(positional))
+}
+
+// SetAllowUndeclaredParameters lets statements use parameters that were
+// not declared. Their types are inferred from their use and reported in
+// AnalyzeOutput.UndeclaredParameters.
+func (ao *AnalyzerOptions) SetAllowUndeclaredParameters(allow bool) {
+	C.zetasql_AnalyzerOptions_SetAllowUndeclaredParameters(ao.raw, C.bool(allow))
+}
+
 // ParseStatement parses a SQL statement and returns any syntax error.
 func ParseStatement(sql string) error {
 	csql := C.CString(sql)
@@ -212,7 +482,7 @@ func ParseStatement(sql string) error {
 	C.zetasql_ParseStatement(csql, &st)
 	status := statusFromC(st)
 	if !status.OK {
-		return fmt.Errorf("parse error: %s", status.Error())
+		return fmt.Errorf("parse error: %w", status)
 	}
 	return nil
 }
@@ -227,21 +497,366 @@ func ParseScript(sql string) error {
 	C.zetasql_ParseScript(csql, &st)
 	status := statusFromC(st)
 	if !status.OK {
-		return fmt.Errorf("parse error: %s", status.Error())
+		return fmt.Errorf("parse error: %w", status)
 	}
 	return nil
 }
 
-// AnalyzeStatement analyzes a SQL statement against a catalog.
-func AnalyzeStatement(sql string, catalog *SimpleCatalog, opts *AnalyzerOptions) error {
+// ASTNode flag bits, mirroring the ZETASQL_AST_* defines in zetasql_bridge.h.
+const (
+	ASTStatement       = C.ZETASQL_AST_STATEMENT
+	ASTScriptStatement = C.ZETASQL_AST_SCRIPT_STATEMENT
+	ASTExpression      = C.ZETASQL_AST_EXPRESSION
+	ASTQueryExpression = C.ZETASQL_AST_QUERY_EXPRESSION
+	ASTTableExpression = C.ZETASQL_AST_TABLE_EXPRESSION
+	ASTType            = C.ZETASQL_AST_TYPE
+)
+
+// ASTNode is one node of a parse tree flattened in pre-order.
+type ASTNode struct {
+	Kind   string // e.g. "QueryStatement", "PathExpression", "IfStatement"
+	Name   string // identifier value or literal image, empty otherwise
+	Parent int    // index of the parent node, -1 for the root
+	Start  int    // byte offset of the first character
+	End    int    // byte offset one past the last character
+	Flags  int    // bitwise OR of the AST* flags
+}
+
+func nodesFromC(nodes *C.zetasql_ASTNode, count C.int) []ASTNode {
+	if nodes == nil {
+		return nil
+	}
+	defer C.zetasql_ASTNodes_free(nodes, count)
+
+	out := make([]ASTNode, int(count))
+	for i, n := range unsafe.Slice(nodes, int(count)) {
+		out[i] = ASTNode{
+			Kind:   C.GoString(n.kind),
+			Parent: int(n.parent),
+			Start:  int(n.start),
+			End:    int(n.end),
+			Flags:  int(n.flags),
+		}
+		if n.name != nil {
+			out[i].Name = C.GoString(n.name)
+		}
+	}
+	return out
+}
+
+// ParseScriptAST parses a SQL script and returns its parse tree in pre-order.
+func ParseScriptAST(sql string) ([]ASTNode, error) {
 	csql := C.CString(sql)
 	defer C.free(unsafe.Pointer(csql))
 
+	var nodes *C.zetasql_ASTNode
+	var count C.int
 	var st C.zetasql_Status
-	C.zetasql_AnalyzeStatement(csql, catalog.raw, opts.raw, &st)
+	C.zetasql_ParseScriptAST(csql, &nodes, &count, &st)
 	status := statusFromC(st)
 	if !status.OK {
-		return fmt.Errorf("analysis error: %s", status.Error())
+		return nil, fmt.Errorf("parse error: %w", status)
 	}
-	return nil
+	return nodesFromC(nodes, count), nil
+}
+
+// ScriptParser parses a script one statement at a time, so callers can
+// resume after a statement that fails to parse.
+type ScriptParser struct {
+	csql   *C.char
+	length int
+}
+
+// NewScriptParser returns a parser over sql. Close must be called to
+// release it.
+func NewScriptParser(sql string) *ScriptParser {
+	return &ScriptParser{csql: C.CString(sql), length: len(sql)}
+}
+
+// Close releases the parser's copy of the input.
+func (p *ScriptParser) Close() {
+	if p.csql != nil {
+		C.free(unsafe.Pointer(p.csql))
+		p.csql = nil
+	}
+}
+
+// Next parses the statement starting at byte offset pos. It returns the
+// statement's parse tree and the offset just past the statement's
+// terminating semicolon. atEnd reports that the input has no further
+// statements. Error locations are relative to the whole input.
+func (p *ScriptParser) Next(pos int) (nodes []ASTNode, next int, atEnd bool, err error) {
+	cpos := C.int(pos)
+	var cnodes *C.zetasql_ASTNode
+	var count C.int
+	var catEnd C.bool
+	var st C.zetasql_Status
+	C.zetasql_ParseNextScriptStatement(p.csql, &cpos, &catEnd, &cnodes, &count, &st)
+	status := statusFromC(st)
+	if !status.OK {
+		return nil, pos, false, fmt.Errorf("parse error: %w", status)
+	}
+	return nodesFromC(cnodes, count), int(cpos), bool(catEnd), nil
+}
+
+// Skip returns the offset just past the next semicolon at or after pos that
+// is not inside a literal or comment, or the input length if there is none.
+func (p *ScriptParser) Skip(pos int) int {
+	if pos >= p.length {
+		return p.length
+	}
+	return int(C.zetasql_SkipToNextStatement(p.csql, C.int(pos)))
+}
+
+// SkipStatement returns the offset just past the statement starting at pos,
+// including any statements nested in its blocks, so that parsing can resume
+// after a syntax error without landing inside a block. It returns -1 if the
+// statement's end cannot be told, e.g. because a block is never closed.
+func (p *ScriptParser) SkipStatement(pos int) int {
+	if pos >= p.length {
+		return p.length
+	}
+	return int(C.zetasql_SkipStatement(p.csql, C.int(pos)))
+}
+
+// NameAndType is a column name with its BigQuery type name.
+type NameAndType struct {
+	Name     string
+	TypeName string
+	Mode     string // "REQUIRED" for a NOT NULL column definition, "" otherwise
+}
+
+// ColumnRef identifies a column read from a table.
+type ColumnRef struct {
+	Table  string
+	Column string
+}
+
+// AnalyzeOutput is the part of ZetaSQL's AnalyzerOutput exposed to Go.
+type AnalyzeOutput struct {
+	StatementKind string // e.g. "QueryStmt", "InsertStmt"
+	OutputColumns []NameAndType
+	Tables        []string // full names of referenced tables
+	Columns       []ColumnRef
+	Warnings      []string
+	Resolved      []ASTNode // resolved AST in pre-order; Start/End are -1 if unknown
+
+	// UndeclaredParameters are the inferred types of the parameters used
+	// without being declared, if allowed: named ones by name, then
+	// positional ones, with an empty Name, in order.
+	UndeclaredParameters []NameAndType
+
+	// DDL statements only.
+	NamePath          []string      // object created, dropped or altered, or procedure called
+	ColumnDefinitions []NameAndType // columns of a created table or view
+	ObjectType        string        // DROP statements, e.g. "TABLE"
+	AlterActions      []AlterAction
+	Arguments         []Argument // CREATE PROCEDURE only
+	Body              string     // CREATE PROCEDURE only
+}
+
+// Argument is an argument of a CREATE PROCEDURE statement.
+type Argument struct {
+	Name     string
+	TypeName string // "ANY TYPE" for a templated argument
+	Mode     string // "IN", "OUT" or "INOUT"
+}
+
+// AlterAction is one action of an ALTER TABLE statement.
+type AlterAction struct {
+	Kind     string // e.g. "AddColumnAction", "DropColumnAction", "RenameColumnAction"
+	Column   string
+	NewName  string // RENAME COLUMN only
+	TypeName string // ADD COLUMN and SET DATA TYPE only
+	Mode     string // "REQUIRED" for an added NOT NULL column
+	IfExists bool   // ADD COLUMN IF NOT EXISTS or DROP COLUMN IF EXISTS
+}
+
+func nameAndTypesFromC(cols *C.zetasql_NameAndType, count C.int) []NameAndType {
+	if cols == nil {
+		return nil
+	}
+	out := make([]NameAndType, int(count))
+	for i, c := range unsafe.Slice(cols, int(count)) {
+		out[i] = NameAndType{Name: C.GoString(c.name), TypeName: C.GoString(c.type_name)}
+		if c.mode != nil {
+			out[i].Mode = C.GoString(c.mode)
+		}
+	}
+	return out
+}
+
+// goStringOrEmpty converts a possibly NULL C string.
+func goStringOrEmpty(s *C.char) string {
+	if s == nil {
+		return ""
+	}
+	return C.GoString(s)
+}
+
+func stringsFromC(strs **C.char, count C.int) []string {
+	if strs == nil {
+		return nil
+	}
+	out := make([]string, int(count))
+	for i, s := range unsafe.Slice(strs, int(count)) {
+		out[i] = C.GoString(s)
+	}
+	return out
+}
+
+func analyzeOutputFromC(o *C.zetasql_AnalyzerOutput) *AnalyzeOutput {
+	defer C.zetasql_AnalyzerOutput_free(o)
+
+	out := &AnalyzeOutput{
+		StatementKind:     C.GoString(o.statement_kind),
+		OutputColumns:     nameAndTypesFromC(o.output_columns, o.output_column_count),
+		Tables:            stringsFromC(o.tables, o.table_count),
+		Warnings:          stringsFromC(o.warnings, o.warning_count),
+		NamePath:          stringsFromC(o.name_path, o.name_path_count),
+		ColumnDefinitions: nameAndTypesFromC(o.column_definitions, o.column_definition_count),
+		ObjectType:        goStringOrEmpty(o.object_type),
+		Body:              goStringOrEmpty(o.body),
+
+		UndeclaredParameters: nameAndTypesFromC(o.undeclared_parameters, o.undeclared_parameter_count),
+	}
+	if o.alter_actions != nil {
+		for _, a := range unsafe.Slice(o.alter_actions, int(o.alter_action_count)) {
+			out.AlterActions = append(out.AlterActions, AlterAction{
+				Kind:     C.GoString(a.kind),
+				Column:   goStringOrEmpty(a.column),
+				NewName:  goStringOrEmpty(a.new_name),
+				TypeName: goStringOrEmpty(a.type_name),
+				Mode:     goStringOrEmpty(a.mode),
+				IfExists: bool(a.if_exists),
+			})
+		}
+	}
+	if o.arguments != nil {
+		for _, a := range unsafe.Slice(o.arguments, int(o.argument_count)) {
+			out.Arguments = append(out.Arguments, Argument{
+				Name:     C.GoString(a.name),
+				TypeName: C.GoString(a.type_name),
+				Mode:     C.GoString(a.mode),
+			})
+		}
+	}
+	if o.columns != nil {
+		for _, c := range unsafe.Slice(o.columns, int(o.column_count)) {
+			out.Columns = append(out.Columns, ColumnRef{
+				Table:  C.GoString(c.table),
+				Column: C.GoString(c.column),
+			})
+		}
+	}
+
+	// The nodes are copied here; AnalyzerOutput_free releases the C array.
+	out.Resolved = nodesFromC(o.resolved_nodes, o.resolved_node_count)
+	o.resolved_nodes = nil
+	o.resolved_node_count = 0
+	return out
+}
+
+// ParsedStatement is a statement parsed for analysis, so that its parse tree
+// can be looked at before it is analyzed without parsing it twice.
+type ParsedStatement struct {
+	raw unsafe.Pointer
+
+	// Nodes is the statement's parse tree in pre-order, if asked for.
+	Nodes []ASTNode
+}
+
+// ParseForAnalysis parses sql for analysis, with the parser options opts
+// implies, and reports errors as Analyze does. With tree set, the
+// parse tree is returned in Nodes. Close must be called to release the
+// statement.
+func ParseForAnalysis(sql string, opts *AnalyzerOptions, tree bool) (*ParsedStatement, error) {
+	csql := C.CString(sql)
+	defer C.free(unsafe.Pointer(csql))
+
+	var nodes *C.zetasql_ASTNode
+	var count C.int
+	pnodes, pcount := &nodes, &count
+	if !tree {
+		pnodes, pcount = nil, nil
+	}
+	var st C.zetasql_Status
+	raw := C.zetasql_ParsedStatement_new(csql, opts.raw, pnodes, pcount, &st)
+	status := statusFromC(st)
+	if !status.OK {
+		return nil, fmt.Errorf("analysis error: %w", status)
+	}
+	return &ParsedStatement{raw: raw, Nodes: nodesFromC(nodes, count)}, nil
+}
+
+// Analyze analyzes the statement against a catalog, with positional (?)
+// rather than named (@name) parameters if positional is set, whatever mode
+// opts selects. opts itself is left alone, so it can be shared.
+func (p *ParsedStatement) Analyze(catalog Catalog, opts *AnalyzerOptions, positional bool) (*AnalyzeOutput, error) {
+	var out C.zetasql_AnalyzerOutput
+	var st C.zetasql_Status
+	C.zetasql_ParsedStatement_Analyze(p.raw, catalog.catalogHandle(), catalog.typeFactory().raw, opts.raw, C.bool(positional), &out, &st)
+	status := statusFromC(st)
+	if !status.OK {
+		return nil, fmt.Errorf("analysis error: %w", status)
+	}
+	return analyzeOutputFromC(&out), nil
+}
+
+// Close releases the statement.
+func (p *ParsedStatement) Close() {
+	if p.raw != nil {
+		C.zetasql_ParsedStatement_free(p.raw)
+		p.raw = nil
+	}
+}
+
+// AnalyzeExpression analyzes a standalone expression against a catalog and
+// returns its type name. If targetType is not empty, the expression must
+// also be assignable to that type.
+func AnalyzeExpression(sql string, catalog Catalog, opts *AnalyzerOptions, targetType string) (string, error) {
+	csql := C.CString(sql)
+	defer C.free(unsafe.Pointer(csql))
+	var ctarget *C.char
+	if targetType != "" {
+		ctarget = C.CString(targetType)
+		defer C.free(unsafe.Pointer(ctarget))
+	}
+
+	var ctype *C.char
+	var st C.zetasql_Status
+	C.zetasql_AnalyzeExpression(csql, catalog.catalogHandle(), catalog.typeFactory().raw, opts.raw, ctarget, &ctype, &st)
+	status := statusFromC(st)
+	if !status.OK {
+		return "", fmt.Errorf("analysis error: %w", status)
+	}
+	defer C.zetasql_free_string(ctype)
+	return C.GoString(ctype), nil
+}
+
+// Keywords returns the GoogleSQL keywords, reserved and not, in upper case.
+func Keywords() []string {
+	var keywords **C.char
+	var count C.int
+	C.zetasql_Keywords(&keywords, &count)
+	defer C.zetasql_free_strings(keywords, count)
+	return stringsFromC(keywords, count)
+}
+
+// ParseType resolves a type string as a column type and returns its
+// canonical spelling, e.g. "STRING(50)" or "STRUCT<a INT64, b ARRAY<DATE>>".
+// Error locations in the returned Status are relative to typeName.
+func ParseType(typeName string, catalog Catalog, opts *AnalyzerOptions) (string, error) {
+	ctype := C.CString(typeName)
+	defer C.free(unsafe.Pointer(ctype))
+
+	var cname *C.char
+	var st C.zetasql_Status
+	C.zetasql_ParseType(ctype, catalog.catalogHandle(), catalog.typeFactory().raw, opts.raw, &cname, &st)
+	status := statusFromC(st)
+	if !status.OK {
+		return "", fmt.Errorf("type error: %w", status)
+	}
+	defer C.zetasql_free_string(cname)
+	return C.GoString(cname), nil
 }
@@ -1,8 +1,10 @@
 #include "zetasql_bridge.h"
 
+#include <algorithm>
 #include <cstdlib>
 #include <cstring>
 #include <memory>
+#include <optional>
 #include <string>
 #include <vector>
 
@@ -10,13 +12,32 @@
 #include "googlesql/public/analyzer_options.h"
 #include "googlesql/public/catalog.h"
 #include "googlesql/public/error_helpers.h"
+#include "googlesql/public/constant.h"
 #include "googlesql/public/language_options.h"
+#include "googlesql/public/multi_catalog.h"
+#include "googlesql/public/parse_location.h"
+#include "googlesql/public/parse_resume_location.h"
+#include "googlesql/public/parse_tokens.h"
+#include "googlesql/public/procedure.h"
 #include "googlesql/public/simple_catalog.h"
+#include "googlesql/public/simple_catalog_util.h"
+#include "googlesql/public/function.h"
+#include "googlesql/public/table_valued_function.h"
 #include "googlesql/public/type.h"
 #include "googlesql/public/types/type_factory.h"
+#include "googlesql/public/value.h"
 #include "googlesql/public/builtin_function_options.h"
+#include "googlesql/parser/keywords.h"
+#include "googlesql/parser/parse_tree.h"
 #include "googlesql/parser/parser.h"
+#include "googlesql/resolved_ast/resolved_ast.h"
+#include "googlesql/resolved_ast/resolved_node.h"
+#include "absl/container/flat_hash_set.h"
 #include "absl/status/status.h"
+#include "absl/strings/ascii.h"
+#include "absl/strings/str_cat.h"
+#include "absl/strings/str_join.h"
+#include "absl/strings/str_split.h"
 #include "absl/strings/string_view.h"
 
 static char* dup_string(const std::string& s) {
@@ -27,16 +48,17 @@ static char* dup_string(const std::string& s) {
 }
 
 static void set_status(zetasql_Status* st, const absl::Status& status) {
+    st->error_line = 0;
+    st->error_column = 0;
+    st->error_offset = -1;
+    st->error_code = static_cast<int>(status.code());
+    st->error_source = ZETASQL_ERROR_OTHER;
     if (status.ok()) {
         st->ok = true;
         st->error_message = nullptr;
-        st->error_line = 0;
-        st->error_column = 0;
     } else {
         st->ok = false;
         st->error_message = dup_string(std::string(status.message()));
-        st->error_line = 0;
-        st->error_column = 0;
 
         googlesql::ErrorLocation location;
         if (googlesql::GetErrorLocation(status, &location)) {
@@ -46,114 +68,690 @@ static void set_status(zetasql_Status* st, const absl::Status& status) {
     }
 }
 
-static absl::Status parse_type(const std::string& type_str,
-                                googlesql::TypeFactory* factory,
-                                const googlesql::Type** out_type) {
-    static const struct { const char* name; googlesql::TypeKind kind; } simple_types[] = {
-        {"INT64", googlesql::TYPE_INT64},
-        {"INT32", googlesql::TYPE_INT32},
-        {"UINT32", googlesql::TYPE_UINT32},
-        {"UINT64", googlesql::TYPE_UINT64},
-        {"FLOAT32", googlesql::TYPE_FLOAT},
-        {"FLOAT64", googlesql::TYPE_DOUBLE},
-        {"FLOAT", googlesql::TYPE_FLOAT},
-        {"DOUBLE", googlesql::TYPE_DOUBLE},
-        {"NUMERIC", googlesql::TYPE_NUMERIC},
-        {"BIGNUMERIC", googlesql::TYPE_BIGNUMERIC},
-        {"BOOL", googlesql::TYPE_BOOL},
-        {"BOOLEAN", googlesql::TYPE_BOOL},
-        {"STRING", googlesql::TYPE_STRING},
-        {"BYTES", googlesql::TYPE_BYTES},
-        {"DATE", googlesql::TYPE_DATE},
-        {"DATETIME", googlesql::TYPE_DATETIME},
-        {"TIME", googlesql::TYPE_TIME},
-        {"TIMESTAMP", googlesql::TYPE_TIMESTAMP},
-        {"GEOGRAPHY", googlesql::TYPE_GEOGRAPHY},
-        {"JSON", googlesql::TYPE_JSON},
-        {"INTERVAL", googlesql::TYPE_INTERVAL},
-    };
-
-    std::string upper = type_str;
-    for (auto& c : upper) c = toupper(c);
-
-    size_t start = upper.find_first_not_of(" \t\n\r");
-    size_t end = upper.find_last_not_of(" \t\n\r");
-    if (start == std::string::npos) {
-        return absl::InvalidArgumentError("Empty type string");
+// set_status_for_input is set_status plus the byte offset of the error
+// location within sql, the text the status refers to.
+static void set_status_for_input(zetasql_Status* st, const absl::Status& status,
+                                 absl::string_view sql) {
+    set_status(st, status);
+    if (st->error_line > 0) {
+        googlesql::ParseLocationTranslator translator(sql);
+        auto offset = translator.GetByteOffsetFromLineAndColumn(
+            st->error_line, st->error_column);
+        if (offset.ok()) st->error_offset = *offset;
+    }
+}
+
+// set_parse_status is set_status_for_input for the parser, whose errors
+// are all syntax errors.
+static void set_parse_status(zetasql_Status* st, const absl::Status& status,
+                             absl::string_view sql) {
+    set_status_for_input(st, status, sql);
+    if (!status.ok()) st->error_source = ZETASQL_ERROR_SYNTAX;
+}
+
+static googlesql::ParserOptions script_parser_options() {
+    googlesql::LanguageOptions lang;
+    lang.EnableMaximumLanguageFeatures();
+    lang.SetSupportsAllStatementKinds();
+    return googlesql::ParserOptions(lang);
+}
+
+// Analysis errors carry nothing but a message and a location, so their
+// source is worked out from the parse tree: the innermost node at the
+// error location, and its ancestors in turn, tell what the analyzer was
+// resolving, and catalog lookups tell whether a name it was resolving
+// exists.
+
+static int start_of(const googlesql::ASTNode* node) {
+    return node->GetParseLocationRange().start().GetByteOffset();
+}
+
+// node_at returns the innermost node under root whose text contains offset.
+static const googlesql::ASTNode* node_at(const googlesql::ASTNode* root, int offset) {
+    for (int i = 0; i < root->num_children(); i++) {
+        const googlesql::ASTNode* child = root->child(i);
+        if (start_of(child) <= offset &&
+            offset < child->GetParseLocationRange().end().GetByteOffset()) {
+            return node_at(child, offset);
+        }
     }
-    upper = upper.substr(start, end - start + 1);
-    std::string original_trimmed = type_str.substr(start, end - start + 1);
+    return root;
+}
 
-    for (const auto& st : simple_types) {
-        if (upper == st.name) {
-            *out_type = googlesql::types::TypeFromSimpleTypeKind(st.kind);
-            return absl::OkStatus();
+// add_scope_names adds to names the lower-cased names a path expression
+// under node may start with: the range variables and columns of the tables
+// it reads, and the aliases, WITH names and function arguments it defines.
+static void add_scope_names(const googlesql::ASTNode* node, googlesql::Catalog* catalog,
+                            absl::flat_hash_set<std::string>* names) {
+    switch (node->node_kind()) {
+    case googlesql::AST_TABLE_PATH_EXPRESSION: {
+        const auto* path = node->GetAsOrDie<googlesql::ASTTablePathExpression>()->path_expr();
+        if (path == nullptr) break;
+        names->insert(absl::AsciiStrToLower(path->last_name()->GetAsString()));
+        const googlesql::Table* table = nullptr;
+        if (catalog->FindTable(path->ToIdentifierVector(), &table).ok()) {
+            for (int i = 0; i < table->NumColumns(); i++) {
+                names->insert(absl::AsciiStrToLower(table->GetColumn(i)->Name()));
+            }
         }
+        break;
+    }
+    case googlesql::AST_ALIAS:
+        names->insert(absl::AsciiStrToLower(
+            node->GetAsOrDie<googlesql::ASTAlias>()->GetAsString()));
+        break;
+    case googlesql::AST_ALIASED_QUERY:
+        names->insert(absl::AsciiStrToLower(
+            node->GetAsOrDie<googlesql::ASTAliasedQuery>()->alias()->GetAsString()));
+        break;
+    case googlesql::AST_FUNCTION_PARAMETER: {
+        const auto* name = node->GetAsOrDie<googlesql::ASTFunctionParameter>()->name();
+        if (name != nullptr) names->insert(absl::AsciiStrToLower(name->GetAsString()));
+        break;
     }
+    default:
+        break;
+    }
+    for (int i = 0; i < node->num_children(); i++) {
+        add_scope_names(node->child(i), catalog, names);
+    }
+}
 
-    if (upper.size() > 7 && upper.substr(0, 6) == "ARRAY<" && upper.back() == '>') {
-        std::string inner = original_trimmed.substr(6, original_trimmed.size() - 7);
-        const googlesql::Type* element_type = nullptr;
-        auto s = parse_type(inner, factory, &element_type);
-        if (!s.ok()) return s;
-        return factory->MakeArrayType(element_type, out_type);
+// has_function reports whether catalog has the function named by path,
+// which may be a dotted name such as NET.HOST or have a SAFE. prefix.
+static bool has_function(googlesql::Catalog* catalog, std::vector<std::string> path) {
+    if (path.size() > 1 && absl::AsciiStrToUpper(path[0]) == "SAFE") {
+        path.erase(path.begin());
     }
+    const googlesql::Function* function = nullptr;
+    return catalog->FindFunction(path, &function).ok() ||
+           catalog->FindFunction({absl::StrJoin(path, ".")}, &function).ok();
+}
 
-    if (upper.size() > 8 && upper.substr(0, 7) == "STRUCT<" && upper.back() == '>') {
-        std::string inner = original_trimmed.substr(7, original_trimmed.size() - 8);
-        std::vector<googlesql::StructType::StructField> fields;
+// is_declared reports whether the parameter a ParameterExpr refers to was
+// declared, or needs not be.
+static bool is_declared(const googlesql::ASTParameterExpr* param,
+                        const googlesql::AnalyzerOptions& options) {
+    if (options.allow_undeclared_parameters()) return true;
+    if (param->name() == nullptr) {
+        return param->position() <= static_cast<int>(options.positional_query_parameters().size());
+    }
+    return options.parameter_mode() != googlesql::PARAMETER_NAMED ||
+           options.query_parameters().contains(absl::AsciiStrToLower(param->name()->GetAsString()));
+}
 
-        int depth = 0;
-        size_t field_start = 0;
-        for (size_t i = 0; i <= inner.size(); i++) {
-            if (i == inner.size() || (inner[i] == ',' && depth == 0)) {
-                std::string field_str = inner.substr(field_start, i - field_start);
-                size_t fs = field_str.find_first_not_of(" \t");
-                size_t fe = field_str.find_last_not_of(" \t");
-                if (fs == std::string::npos) {
-                    return absl::InvalidArgumentError("Empty field in STRUCT");
-                }
-                field_str = field_str.substr(fs, fe - fs + 1);
-
-                size_t split = std::string::npos;
-                int d2 = 0;
-                for (size_t j = 0; j < field_str.size(); j++) {
-                    if (field_str[j] == '<') d2++;
-                    else if (field_str[j] == '>') d2--;
-                    else if (field_str[j] == ' ' && d2 == 0) {
-                        split = j;
-                        break;
-                    }
-                }
-                if (split == std::string::npos) {
-                    return absl::InvalidArgumentError(
-                        "Invalid STRUCT field (expected 'name type'): " + field_str);
+// path_error returns the source of an error at offset in path, or -1 if
+// the path is not what the error is about.
+static int path_error(const googlesql::ASTPathExpression* path, int offset,
+                      const googlesql::ASTNode* root, googlesql::Catalog* catalog) {
+    std::vector<std::string> names = path->ToIdentifierVector();
+    const googlesql::ASTNode* parent = path->parent();
+    switch (parent->node_kind()) {
+    case googlesql::AST_TABLE_PATH_EXPRESSION: {
+        const googlesql::Table* table = nullptr;
+        return catalog->FindTable(names, &table).ok() ? -1 : ZETASQL_ERROR_UNKNOWN_TABLE;
+    }
+    case googlesql::AST_FUNCTION_CALL:
+        if (parent->GetAsOrDie<googlesql::ASTFunctionCall>()->function() != path) break;
+        return has_function(catalog, names) ? ZETASQL_ERROR_SIGNATURE_MISMATCH
+                                            : ZETASQL_ERROR_UNKNOWN_FUNCTION;
+    case googlesql::AST_TVF: {
+        if (parent->GetAsOrDie<googlesql::ASTTVF>()->name() != path) break;
+        const googlesql::TableValuedFunction* tvf = nullptr;
+        return catalog->FindTableValuedFunction(names, &tvf).ok()
+                   ? ZETASQL_ERROR_SIGNATURE_MISMATCH
+                   : ZETASQL_ERROR_UNKNOWN_FUNCTION;
+    }
+    case googlesql::AST_CALL_STATEMENT: {
+        const googlesql::Procedure* procedure = nullptr;
+        return catalog->FindProcedure(names, &procedure).ok()
+                   ? ZETASQL_ERROR_SIGNATURE_MISMATCH
+                   : ZETASQL_ERROR_UNKNOWN_FUNCTION;
+    }
+    case googlesql::AST_SIMPLE_TYPE:
+        return ZETASQL_ERROR_UNKNOWN_TYPE;
+    default:
+        if (parent->IsStatement()) {
+            // The target of INSERT, UPDATE, DELETE, MERGE and the like.
+            const googlesql::Table* table = nullptr;
+            return catalog->FindTable(names, &table).ok() ? -1 : ZETASQL_ERROR_UNKNOWN_TABLE;
+        }
+        break;
+    }
+
+    // A name in an expression: the error is about the path if it names
+    // nothing in scope, or is at one of its later parts, a field.
+    if (offset > start_of(path)) return ZETASQL_ERROR_UNKNOWN_COLUMN;
+    absl::flat_hash_set<std::string> scope;
+    add_scope_names(root, catalog, &scope);
+    if (scope.contains(absl::AsciiStrToLower(names[0]))) return -1;
+    const googlesql::Constant* constant = nullptr;
+    if (catalog->FindConstant(names, &constant).ok() ||
+        catalog->FindConstant({names[0]}, &constant).ok()) {
+        return -1;
+    }
+    return ZETASQL_ERROR_UNKNOWN_COLUMN;
+}
+
+// classify_error returns the source of an analysis error at offset in the
+// statement or expression with parse tree root, analyzed with catalog and
+// options.
+static int classify_error(const googlesql::ASTNode* root, int offset,
+                          googlesql::Catalog* catalog,
+                          const googlesql::AnalyzerOptions& options) {
+    if (root == nullptr || offset < 0) return ZETASQL_ERROR_OTHER;
+    const googlesql::ASTNode* child = nullptr;
+    for (const googlesql::ASTNode* node = node_at(root, offset); node != nullptr;
+         child = node, node = node == root ? nullptr : node->parent()) {
+        bool at_start = start_of(node) == offset;
+        switch (node->node_kind()) {
+        case googlesql::AST_PATH_EXPRESSION: {
+            int source = path_error(node->GetAsOrDie<googlesql::ASTPathExpression>(),
+                                    offset, root, catalog);
+            if (source >= 0) return source;
+            break;
+        }
+        case googlesql::AST_SIMPLE_TYPE:
+            return ZETASQL_ERROR_UNKNOWN_TYPE;
+        case googlesql::AST_PARAMETER_EXPR:
+            if (!is_declared(node->GetAsOrDie<googlesql::ASTParameterExpr>(), options)) {
+                return ZETASQL_ERROR_UNKNOWN_PARAMETER;
+            }
+            break;
+        case googlesql::AST_DOT_IDENTIFIER:
+            if (at_start || child == node->GetAsOrDie<googlesql::ASTDotIdentifier>()->name()) {
+                return ZETASQL_ERROR_UNKNOWN_COLUMN;
+            }
+            break;
+        case googlesql::AST_BINARY_EXPRESSION:
+        case googlesql::AST_UNARY_EXPRESSION:
+        case googlesql::AST_BETWEEN_EXPRESSION:
+        case googlesql::AST_IN_EXPRESSION:
+        case googlesql::AST_LIKE_EXPRESSION:
+        case googlesql::AST_BITWISE_SHIFT_EXPRESSION:
+        case googlesql::AST_ARRAY_ELEMENT:
+            if (at_start) return ZETASQL_ERROR_SIGNATURE_MISMATCH;
+            break;
+        case googlesql::AST_CAST_EXPRESSION:
+            return ZETASQL_ERROR_TYPE_MISMATCH;
+        case googlesql::AST_INSERT_VALUES_ROW:
+            // A value that does not fit its column.
+            if (child != nullptr) return ZETASQL_ERROR_TYPE_MISMATCH;
+            break;
+        case googlesql::AST_UPDATE_SET_VALUE:
+            if (child != nullptr &&
+                child == node->GetAsOrDie<googlesql::ASTUpdateSetValue>()->value()) {
+                return ZETASQL_ERROR_TYPE_MISMATCH;
+            }
+            break;
+        case googlesql::AST_SELECT_COLUMN:
+            // A column of INSERT ... SELECT that does not fit its column.
+            for (const auto* n = node->parent(); n != nullptr; n = n->parent()) {
+                if (n->node_kind() == googlesql::AST_INSERT_STATEMENT) {
+                    if (at_start) return ZETASQL_ERROR_TYPE_MISMATCH;
+                    break;
                 }
+            }
+            break;
+        default:
+            break;
+        }
+    }
+    return ZETASQL_ERROR_OTHER;
+}
+
+// classify_statement sets the source of an analysis error in sql. sql is
+// parsed again to find where the error is: this is only done for failed
+// statements.
+static void classify_statement(zetasql_Status* st, absl::string_view sql,
+                               googlesql::Catalog* catalog,
+                               const googlesql::AnalyzerOptions& options) {
+    std::unique_ptr<googlesql::ParserOutput> output;
+    if (!googlesql::ParseStatement(sql, options.GetParserOptions(), &output).ok()) {
+        st->error_source = ZETASQL_ERROR_SYNTAX;
+        return;
+    }
+    st->error_source = classify_error(output->statement(), st->error_offset, catalog, options);
+}
+
+// Types are resolved with ZetaSQL's type analysis where possible. NOT NULL
+// STRUCT fields are only accepted by the column definition grammar, so a
+// type it rejects is tried again as the only column of a CREATE TABLE
+// statement, which accepts everything a BigQuery column type can be:
+// parameterized types such as STRING(50) and NUMERIC(10, 2), RANGE<DATE>,
+// quoted STRUCT field names and NOT NULL fields. That statement's errors
+// are the ones reported. Their locations refer to the wrapper statement;
+// set_type_status maps them back into the type.
+static constexpr absl::string_view kTypePrefix = "CREATE TEMP TABLE t (c ";
+
+static std::string type_wrapper(absl::string_view type_str) {
+    // The newline keeps a trailing comment from swallowing the parenthesis.
+    return absl::StrCat(kTypePrefix, type_str, "\n)");
+}
+
+// column_type_params returns the full type parameters of a column
+// definition, e.g. the 50 of STRING(50), including those of nested fields.
+static googlesql::TypeParameters column_type_params(
+    const googlesql::ResolvedColumnDefinition* def) {
+    if (def->annotations() != nullptr) {
+        auto params = def->annotations()->GetFullTypeParameters();
+        if (params.ok()) return *params;
+    }
+    return googlesql::TypeParameters();
+}
+
+// has_column_attributes reports whether annotations give the column, or
+// any of its fields, more than NOT NULL and type parameters, e.g. OPTIONS
+// or a collation.
+static bool has_column_attributes(const googlesql::ResolvedColumnAnnotations* annotations) {
+    if (annotations == nullptr) return false;
+    if (annotations->option_list_size() > 0 || annotations->collation_name() != nullptr) {
+        return true;
+    }
+    for (int i = 0; i < annotations->child_list_size(); i++) {
+        if (has_column_attributes(annotations->child_list(i))) return true;
+    }
+    return false;
+}
+
+static absl::Status parse_type(absl::string_view type_str,
+                               googlesql::Catalog* catalog,
+                               googlesql::TypeFactory* factory,
+                               const googlesql::AnalyzerOptions& options,
+                               const googlesql::Type** out_type,
+                               googlesql::TypeParameters* out_params) {
+    if (absl::StripAsciiWhitespace(type_str).empty()) {
+        return absl::InvalidArgumentError("Empty type string");
+    }
+    googlesql::TypeModifiers modifiers;
+    if (googlesql::AnalyzeType(std::string(type_str), options, catalog, factory,
+                               out_type, &modifiers).ok()) {
+        if (!modifiers.collation().Empty()) {
+            return absl::InvalidArgumentError(absl::StrCat("Invalid type: ", type_str));
+        }
+        if (out_params != nullptr) *out_params = modifiers.type_parameters();
+        return absl::OkStatus();
+    }
+
+    std::unique_ptr<const googlesql::AnalyzerOutput> output;
+    auto s = googlesql::AnalyzeStatement(
+        type_wrapper(type_str), options, catalog, factory, &output);
+    if (!s.ok()) return s;
 
-                std::string field_name = field_str.substr(0, split);
-                std::string field_type_str = field_str.substr(split + 1);
-                size_t fts = field_type_str.find_first_not_of(" \t");
-                if (fts != std::string::npos) field_type_str = field_type_str.substr(fts);
-
-                const googlesql::Type* field_type = nullptr;
-                auto s = parse_type(field_type_str, factory, &field_type);
-                if (!s.ok()) return s;
-                fields.push_back({field_name, field_type});
-
-                field_start = i + 1;
-            } else if (inner[i] == '<') {
-                depth++;
-            } else if (inner[i] == '>') {
-                depth--;
+    // Reject input that closes the column list and carries on with more of
+    // the statement, e.g. "INT64, d STRING" or "INT64) AS SELECT 1", and
+    // anything a column definition has besides its type, e.g.
+    // "INT64 DEFAULT 1" or "INT64 OPTIONS(description = 'x')".
+    const auto* stmt = output->resolved_statement();
+    if (stmt->node_kind() != googlesql::RESOLVED_CREATE_TABLE_STMT) {
+        return absl::InvalidArgumentError(absl::StrCat("Invalid type: ", type_str));
+    }
+    const auto* create = stmt->GetAs<googlesql::ResolvedCreateTableStmt>();
+    if (create->column_definition_list_size() != 1 ||
+        create->option_list_size() != 0 ||
+        create->partition_by_list_size() != 0 ||
+        create->cluster_by_list_size() != 0 ||
+        create->primary_key() != nullptr ||
+        create->foreign_key_list_size() != 0 ||
+        create->check_constraint_list_size() != 0) {
+        return absl::InvalidArgumentError(absl::StrCat("Invalid type: ", type_str));
+    }
+    const auto* def = create->column_definition_list(0);
+    if (def->default_value() != nullptr || def->generated_column_info() != nullptr ||
+        def->is_hidden() || has_column_attributes(def->annotations())) {
+        return absl::InvalidArgumentError(absl::StrCat("Invalid type: ", type_str));
+    }
+    *out_type = def->type();
+    if (out_params != nullptr) *out_params = column_type_params(def);
+    return absl::OkStatus();
+}
+
+// set_type_status is set_status for an error from parse_type, with the
+// error location mapped from the wrapper statement back into type_str.
+static void set_type_status(zetasql_Status* st, const absl::Status& status,
+                            absl::string_view type_str) {
+    std::string wrapper = type_wrapper(type_str);
+    set_status_for_input(st, status, wrapper);
+    if (st->error_offset < 0) return;
+
+    // An error at a type name is about an unknown type.
+    std::unique_ptr<googlesql::ParserOutput> output;
+    if (!googlesql::ParseStatement(wrapper, script_parser_options(), &output).ok()) {
+        st->error_source = ZETASQL_ERROR_SYNTAX;
+    } else {
+        for (const auto* n = node_at(output->statement(), st->error_offset); n != nullptr;
+             n = n->parent()) {
+            if (n->node_kind() == googlesql::AST_SIMPLE_TYPE) {
+                st->error_source = ZETASQL_ERROR_UNKNOWN_TYPE;
+                break;
             }
         }
+    }
+
+    int offset = std::clamp<int>(st->error_offset - static_cast<int>(kTypePrefix.size()),
+                                 0, static_cast<int>(type_str.size()));
+    absl::string_view before = type_str.substr(0, offset);
+    size_t line_start = before.rfind('\n');
+    line_start = line_start == absl::string_view::npos ? 0 : line_start + 1;
+    st->error_offset = offset;
+    st->error_line = std::count(before.begin(), before.end(), '\n') + 1;
+    // Columns count characters, as bigq.Position does, not bytes.
+    absl::string_view line = before.substr(line_start);
+    st->error_column = static_cast<int>(std::count_if(
+        line.begin(), line.end(), [](char c) { return (c & 0xC0) != 0x80; })) + 1;
+}
+
+static void flatten_ast(const googlesql::ASTNode* node, int parent,
+                        std::vector<zetasql_ASTNode>* out) {
+    zetasql_ASTNode n;
+    n.kind = dup_string(node->GetNodeKindString());
+    n.name = nullptr;
+    if (node->node_kind() == googlesql::AST_IDENTIFIER) {
+        n.name = dup_string(node->GetAsOrDie<googlesql::ASTIdentifier>()->GetAsString());
+    } else if (node->IsLeaf()) {
+        n.name = dup_string(std::string(
+            static_cast<const googlesql::ASTLeaf*>(node)->image()));
+    }
+    n.parent = parent;
+
+    const googlesql::ParseLocationRange& range = node->GetParseLocationRange();
+    n.start = range.start().GetByteOffset();
+    n.end = range.end().GetByteOffset();
+
+    n.flags = 0;
+    if (node->IsStatement()) n.flags |= ZETASQL_AST_STATEMENT;
+    if (node->IsScriptStatement()) n.flags |= ZETASQL_AST_SCRIPT_STATEMENT;
+    if (node->IsExpression()) n.flags |= ZETASQL_AST_EXPRESSION;
+    if (node->IsQueryExpression()) n.flags |= ZETASQL_AST_QUERY_EXPRESSION;
+    if (node->IsTableExpression()) n.flags |= ZETASQL_AST_TABLE_EXPRESSION;
+    if (node->IsType()) n.flags |= ZETASQL_AST_TYPE;
+
+    int index = static_cast<int>(out->size());
+    out->push_back(n);
+    for (int i = 0; i < node->num_children(); i++) {
+        flatten_ast(node->child(i), index, out);
+    }
+}
+
+static void copy_nodes(const std::vector<zetasql_ASTNode>& nodes,
+                       zetasql_ASTNode** out, int* count) {
+    *count = static_cast<int>(nodes.size());
+    *out = static_cast<zetasql_ASTNode*>(malloc(sizeof(zetasql_ASTNode) * nodes.size()));
+    memcpy(*out, nodes.data(), sizeof(zetasql_ASTNode) * nodes.size());
+}
+
+static void flatten_resolved(const googlesql::ResolvedNode* node, int parent,
+                             std::vector<zetasql_ASTNode>* out) {
+    zetasql_ASTNode n;
+    n.kind = dup_string(node->node_kind_string());
+    n.name = nullptr;
+    if (node->node_kind() == googlesql::RESOLVED_TABLE_SCAN) {
+        n.name = dup_string(node->GetAs<googlesql::ResolvedTableScan>()->table()->FullName());
+    } else if (node->node_kind() == googlesql::RESOLVED_COLUMN_REF) {
+        n.name = dup_string(node->GetAs<googlesql::ResolvedColumnRef>()->column().name());
+    }
+    n.parent = parent;
+    n.start = -1;
+    n.end = -1;
+    if (const auto* range = node->GetParseLocationRangeOrNULL()) {
+        n.start = range->start().GetByteOffset();
+        n.end = range->end().GetByteOffset();
+    }
+    n.flags = 0;
+
+    int index = static_cast<int>(out->size());
+    out->push_back(n);
+    std::vector<const googlesql::ResolvedNode*> children;
+    node->GetChildNodes(&children);
+    for (const auto* child : children) {
+        flatten_resolved(child, index, out);
+    }
+}
+
+static char** dup_strings(const std::vector<std::string>& strs) {
+    auto** out = static_cast<char**>(malloc(sizeof(char*) * strs.size()));
+    for (size_t i = 0; i < strs.size(); i++) out[i] = dup_string(strs[i]);
+    return out;
+}
+
+static void free_strings(char** strs, int count) {
+    for (int i = 0; i < count; i++) free(strs[i]);
+    free(strs);
+}
+
+static void copy_name_types(const std::vector<zetasql_NameAndType>& cols,
+                            zetasql_NameAndType** out, int* count) {
+    *count = static_cast<int>(cols.size());
+    *out = static_cast<zetasql_NameAndType*>(malloc(sizeof(zetasql_NameAndType) * cols.size()));
+    memcpy(*out, cols.data(), sizeof(zetasql_NameAndType) * cols.size());
+}
+
+static zetasql_NameAndType name_and_type(const std::string& name, const googlesql::Type* type) {
+    return {dup_string(name), dup_string(type->TypeName(googlesql::PRODUCT_EXTERNAL)), nullptr};
+}
 
-        return factory->MakeStructType(fields, out_type);
+// column_name_and_type is name_and_type for a column definition, keeping
+// type parameters such as STRING(50) and marking NOT NULL columns REQUIRED.
+static zetasql_NameAndType column_name_and_type(const googlesql::ResolvedColumnDefinition* def) {
+    auto name = def->type()->TypeNameWithParameters(
+        column_type_params(def), googlesql::PRODUCT_EXTERNAL);
+    zetasql_NameAndType col = name.ok()
+        ? zetasql_NameAndType{dup_string(def->name()), dup_string(*name), nullptr}
+        : name_and_type(def->name(), def->type());
+    if (def->annotations() != nullptr && def->annotations()->not_null()) {
+        col.mode = dup_string("REQUIRED");
     }
+    return col;
+}
 
-    return absl::InvalidArgumentError("Unknown type: " + type_str);
+static std::vector<zetasql_NameAndType> output_columns(
+    const std::vector<std::unique_ptr<const googlesql::ResolvedOutputColumn>>& list) {
+    std::vector<zetasql_NameAndType> cols;
+    for (const auto& col : list) {
+        cols.push_back(name_and_type(col->name(), col->column().type()));
+    }
+    return cols;
 }
 
+// procedure_arguments returns the arguments of a CREATE PROCEDURE statement.
+static std::vector<zetasql_Argument> procedure_arguments(
+    const googlesql::ResolvedCreateProcedureStmt* create) {
+    std::vector<zetasql_Argument> args;
+    const auto& signature_args = create->signature().arguments();
+    for (int i = 0; i < static_cast<int>(signature_args.size()); i++) {
+        const auto& arg = signature_args[i];
+        const char* mode = "IN";
+        switch (arg.options().procedure_argument_mode()) {
+        case googlesql::FunctionEnums::OUT:
+            mode = "OUT";
+            break;
+        case googlesql::FunctionEnums::INOUT:
+            mode = "INOUT";
+            break;
+        default:
+            break;
+        }
+        std::string type_name = arg.type() != nullptr
+            ? arg.type()->TypeName(googlesql::PRODUCT_EXTERNAL)
+            : "ANY TYPE";
+        args.push_back({dup_string(create->argument_name_list(i)),
+                        dup_string(type_name), dup_string(mode)});
+    }
+    return args;
+}
+
+// fill_ddl_output records what a CREATE, DROP or ALTER TABLE statement does
+// to the catalog, so callers can track script-local tables and routines, and
+// which procedure a CALL statement calls.
+static void fill_ddl_output(const googlesql::ResolvedStatement* stmt,
+                            zetasql_AnalyzerOutput* out) {
+    std::vector<std::string> name_path;
+    std::vector<zetasql_NameAndType> cols;
+    std::vector<zetasql_AlterAction> actions;
+    std::vector<zetasql_Argument> args;
+
+    switch (stmt->node_kind()) {
+    case googlesql::RESOLVED_CREATE_TABLE_STMT:
+    case googlesql::RESOLVED_CREATE_EXTERNAL_TABLE_STMT: {
+        const auto* create = stmt->GetAs<googlesql::ResolvedCreateTableStmtBase>();
+        name_path = create->name_path();
+        for (const auto& def : create->column_definition_list()) {
+            cols.push_back(column_name_and_type(def.get()));
+        }
+        break;
+    }
+    case googlesql::RESOLVED_CREATE_TABLE_AS_SELECT_STMT: {
+        const auto* create = stmt->GetAs<googlesql::ResolvedCreateTableAsSelectStmt>();
+        name_path = create->name_path();
+        cols = output_columns(create->output_column_list());
+        break;
+    }
+    case googlesql::RESOLVED_CREATE_VIEW_STMT:
+    case googlesql::RESOLVED_CREATE_MATERIALIZED_VIEW_STMT: {
+        const auto* create = stmt->GetAs<googlesql::ResolvedCreateViewBase>();
+        name_path = create->name_path();
+        cols = output_columns(create->output_column_list());
+        break;
+    }
+    case googlesql::RESOLVED_CREATE_FUNCTION_STMT:
+        name_path = stmt->GetAs<googlesql::ResolvedCreateFunctionStmt>()->name_path();
+        break;
+    case googlesql::RESOLVED_CREATE_TABLE_FUNCTION_STMT: {
+        const auto* create = stmt->GetAs<googlesql::ResolvedCreateTableFunctionStmt>();
+        name_path = create->name_path();
+        cols = output_columns(create->output_column_list());
+        break;
+    }
+    case googlesql::RESOLVED_CREATE_PROCEDURE_STMT: {
+        const auto* create = stmt->GetAs<googlesql::ResolvedCreateProcedureStmt>();
+        name_path = create->name_path();
+        args = procedure_arguments(create);
+        out->body = dup_string(create->procedure_body());
+        break;
+    }
+    case googlesql::RESOLVED_CALL_STMT:
+        name_path = stmt->GetAs<googlesql::ResolvedCallStmt>()->procedure()->FunctionNamePath();
+        break;
+    case googlesql::RESOLVED_DROP_STMT: {
+        const auto* drop = stmt->GetAs<googlesql::ResolvedDropStmt>();
+        name_path = drop->name_path();
+        out->object_type = dup_string(drop->object_type());
+        break;
+    }
+    case googlesql::RESOLVED_ALTER_TABLE_STMT: {
+        const auto* alter = stmt->GetAs<googlesql::ResolvedAlterTableStmt>();
+        name_path = alter->name_path();
+        for (const auto& action : alter->alter_action_list()) {
+            zetasql_AlterAction a = {dup_string(action->node_kind_string()), nullptr, nullptr,
+                                     nullptr, nullptr, false};
+            switch (action->node_kind()) {
+            case googlesql::RESOLVED_ADD_COLUMN_ACTION: {
+                const auto* add = action->GetAs<googlesql::ResolvedAddColumnAction>();
+                zetasql_NameAndType col = column_name_and_type(add->column_definition());
+                a.column = col.name;
+                a.type_name = col.type_name;
+                a.mode = col.mode;
+                a.if_exists = add->is_if_not_exists();
+                break;
+            }
+            case googlesql::RESOLVED_DROP_COLUMN_ACTION: {
+                const auto* drop = action->GetAs<googlesql::ResolvedDropColumnAction>();
+                a.column = dup_string(drop->name());
+                a.if_exists = drop->is_if_exists();
+                break;
+            }
+            case googlesql::RESOLVED_RENAME_COLUMN_ACTION: {
+                const auto* rename = action->GetAs<googlesql::ResolvedRenameColumnAction>();
+                a.column = dup_string(rename->name());
+                a.new_name = dup_string(rename->new_name());
+                break;
+            }
+            case googlesql::RESOLVED_ALTER_COLUMN_SET_DATA_TYPE_ACTION: {
+                const auto* set = action->GetAs<googlesql::ResolvedAlterColumnSetDataTypeAction>();
+                a.column = dup_string(set->column());
+                a.type_name = dup_string(set->updated_type()->TypeName(googlesql::PRODUCT_EXTERNAL));
+                break;
+            }
+            default:
+                break;
+            }
+            actions.push_back(a);
+        }
+        break;
+    }
+    default:
+        return;
+    }
+
+    out->name_path_count = static_cast<int>(name_path.size());
+    out->name_path = dup_strings(name_path);
+    copy_name_types(cols, &out->column_definitions, &out->column_definition_count);
+    out->alter_action_count = static_cast<int>(actions.size());
+    out->alter_actions = static_cast<zetasql_AlterAction*>(
+        malloc(sizeof(zetasql_AlterAction) * actions.size()));
+    memcpy(out->alter_actions, actions.data(), sizeof(zetasql_AlterAction) * actions.size());
+    out->argument_count = static_cast<int>(args.size());
+    out->arguments = static_cast<zetasql_Argument*>(
+        malloc(sizeof(zetasql_Argument) * args.size()));
+    memcpy(out->arguments, args.data(), sizeof(zetasql_Argument) * args.size());
+}
+
+static void fill_analyzer_output(const googlesql::AnalyzerOutput& output,
+                                 zetasql_AnalyzerOutput* out) {
+    const googlesql::ResolvedStatement* stmt = output.resolved_statement();
+    out->statement_kind = dup_string(stmt->node_kind_string());
+
+    std::vector<zetasql_NameAndType> cols;
+    if (stmt->node_kind() == googlesql::RESOLVED_QUERY_STMT) {
+        cols = output_columns(stmt->GetAs<googlesql::ResolvedQueryStmt>()->output_column_list());
+    }
+    copy_name_types(cols, &out->output_columns, &out->output_column_count);
+    fill_ddl_output(stmt, out);
+
+    std::vector<zetasql_ASTNode> nodes;
+    flatten_resolved(stmt, -1, &nodes);
+    copy_nodes(nodes, &out->resolved_nodes, &out->resolved_node_count);
+
+    std::vector<std::string> names;
+    for (const auto& param : output.undeclared_parameters()) names.push_back(param.first);
+    std::sort(names.begin(), names.end());
+    std::vector<zetasql_NameAndType> params;
+    for (const auto& name : names) {
+        params.push_back(name_and_type(name, output.undeclared_parameters().at(name)));
+    }
+    for (const auto* type : output.undeclared_positional_parameters()) {
+        params.push_back(name_and_type("", type));
+    }
+    copy_name_types(params, &out->undeclared_parameters, &out->undeclared_parameter_count);
+
+    // With pruned columns every table scan lists just the columns it reads.
+    std::vector<std::string> tables;
+    std::vector<zetasql_ColumnRef> refs;
+    std::vector<const googlesql::ResolvedNode*> scans;
+    stmt->GetDescendantsWithKinds({googlesql::RESOLVED_TABLE_SCAN}, &scans);
+    for (const auto* node : scans) {
+        const auto* scan = node->GetAs<googlesql::ResolvedTableScan>();
+        std::string table = scan->table()->FullName();
+        if (std::find(tables.begin(), tables.end(), table) == tables.end()) {
+            tables.push_back(table);
+        }
+        for (const auto& col : scan->column_list()) {
+            refs.push_back({dup_string(table), dup_string(col.name())});
+        }
+    }
+    out->table_count = static_cast<int>(tables.size());
+    out->tables = dup_strings(tables);
+    out->column_count = static_cast<int>(refs.size());
+    out->columns = static_cast<zetasql_ColumnRef*>(malloc(sizeof(zetasql_ColumnRef) * refs.size()));
+    memcpy(out->columns, refs.data(), sizeof(zetasql_ColumnRef) * refs.size());
+
+    std::vector<std::string> warnings;
+    for (const auto& w : output.deprecation_warnings()) {
+        warnings.push_back(std::string(w.message()));
+    }
+    out->warning_count = static_cast<int>(warnings.size());
+    out->warnings = dup_strings(warnings);
+}
 
 extern "C" {
 
@@ -210,38 +808,227 @@ void* zetasql_SimpleCatalog_AddSubCatalog(void* catalog, const char* name) {
     return static_cast<void*>(sub);
 }
 
-void zetasql_SimpleCatalog_AddTable(void* catalog, void* table) {
-    static_cast<googlesql::SimpleCatalog*>(catalog)->AddTable(
-        static_cast<googlesql::SimpleTable*>(table));
+void zetasql_SimpleCatalog_AddTable(
+    void* catalog, const char* name, void* table, bool owned) {
+    auto* cat = static_cast<googlesql::SimpleCatalog*>(catalog);
+    auto* t = static_cast<googlesql::SimpleTable*>(table);
+    if (owned) {
+        // The catalog owns the table so that freeing it releases it too.
+        cat->AddOwnedTable(name, t);
+    } else {
+        cat->AddTable(name, t);
+    }
+}
+
+void zetasql_SimpleCatalog_AddConstant(
+    void* catalog, const char* name, const char* type_name, void* opts,
+    zetasql_Status* status) {
+    auto* cat = static_cast<googlesql::SimpleCatalog*>(catalog);
+    const googlesql::Type* type = nullptr;
+    auto s = parse_type(type_name, cat, cat->type_factory(),
+                        *static_cast<googlesql::AnalyzerOptions*>(opts), &type, nullptr);
+    if (!s.ok()) {
+        set_type_status(status, s, type_name);
+        return;
+    }
+    std::unique_ptr<googlesql::SimpleConstant> constant;
+    s = googlesql::SimpleConstant::Create({name}, googlesql::Value::Null(type), &constant);
+    if (s.ok()) {
+        cat->AddOwnedConstant(name, std::move(constant));
+    }
+    set_status(status, s);
+}
+
+void* zetasql_SimpleCatalog_AsCatalog(void* catalog) {
+    return static_cast<void*>(static_cast<googlesql::Catalog*>(
+        static_cast<googlesql::SimpleCatalog*>(catalog)));
+}
+
+void zetasql_SimpleCatalog_FunctionNames(void* catalog, char*** names, int* count) {
+    auto* cat = static_cast<googlesql::SimpleCatalog*>(catalog);
+    std::vector<std::string> out = cat->function_names();
+    for (const std::string& name : cat->table_valued_function_names()) {
+        out.push_back(name);
+    }
+    *names = dup_strings(out);
+    *count = static_cast<int>(out.size());
+}
+
+void* zetasql_MultiCatalog_new(
+    const char* name, void** catalogs, int catalog_count, zetasql_Status* status) {
+    std::vector<googlesql::Catalog*> list;
+    for (int i = 0; i < catalog_count; i++) {
+        list.push_back(static_cast<googlesql::Catalog*>(catalogs[i]));
+    }
+    std::unique_ptr<googlesql::MultiCatalog> multi;
+    auto s = googlesql::MultiCatalog::Create(name, list, &multi);
+    set_status(status, s);
+    if (!s.ok()) return nullptr;
+    return static_cast<void*>(static_cast<googlesql::Catalog*>(multi.release()));
+}
+
+void zetasql_MultiCatalog_free(void* catalog) {
+    delete static_cast<googlesql::Catalog*>(catalog);
+}
+
+// A routine is a function, table-valued function or procedure defined by a
+// CREATE statement; exactly one of function, tvf and procedure is set. It
+// owns the analyzer output the definition came from, since SQL bodies
+// refer into the resolved AST.
+struct zetasql_Routine {
+    std::unique_ptr<const googlesql::AnalyzerOutput> output;
+    std::unique_ptr<googlesql::Function> function;
+    std::unique_ptr<googlesql::TableValuedFunction> tvf;
+    std::unique_ptr<googlesql::Procedure> procedure;
+};
+
+void* zetasql_Routine_new(
+    const char* sql, void* catalog, void* factory, void* opts,
+    zetasql_AnalyzerOutput* out, zetasql_Status* status) {
+    memset(out, 0, sizeof(*out));
+    auto routine = std::make_unique<zetasql_Routine>();
+    auto s = googlesql::AnalyzeStatement(
+        sql,
+        *static_cast<googlesql::AnalyzerOptions*>(opts),
+        static_cast<googlesql::Catalog*>(catalog),
+        static_cast<googlesql::TypeFactory*>(factory),
+        &routine->output);
+    if (!s.ok()) {
+        set_status_for_input(status, s, sql);
+        classify_statement(status, sql, static_cast<googlesql::Catalog*>(catalog),
+                           *static_cast<googlesql::AnalyzerOptions*>(opts));
+        return nullptr;
+    }
+
+    const auto* stmt = routine->output->resolved_statement();
+    switch (stmt->node_kind()) {
+    case googlesql::RESOLVED_CREATE_FUNCTION_STMT: {
+        auto function = googlesql::MakeFunctionFromCreateFunction(
+            *stmt->GetAs<googlesql::ResolvedCreateFunctionStmt>());
+        if (!function.ok()) {
+            set_status(status, function.status());
+            return nullptr;
+        }
+        routine->function = std::move(*function);
+        break;
+    }
+    case googlesql::RESOLVED_CREATE_TABLE_FUNCTION_STMT: {
+        auto tvf = googlesql::MakeTVFFromCreateTableFunction(
+            *stmt->GetAs<googlesql::ResolvedCreateTableFunctionStmt>());
+        if (!tvf.ok()) {
+            set_status(status, tvf.status());
+            return nullptr;
+        }
+        routine->tvf = std::move(*tvf);
+        break;
+    }
+    case googlesql::RESOLVED_CREATE_PROCEDURE_STMT: {
+        // Procedure bodies are scripts, which the analyzer leaves alone;
+        // only the signature is needed to check CALL statements.
+        const auto* create = stmt->GetAs<googlesql::ResolvedCreateProcedureStmt>();
+        routine->procedure = std::make_unique<googlesql::Procedure>(
+            create->name_path(), create->signature());
+        break;
+    }
+    default:
+        set_status(status, absl::InvalidArgumentError(absl::StrCat(
+            "expected CREATE FUNCTION, CREATE TABLE FUNCTION or CREATE PROCEDURE, got ",
+            stmt->node_kind_string())));
+        return nullptr;
+    }
+    fill_analyzer_output(*routine->output, out);
+    set_status(status, absl::OkStatus());
+    return static_cast<void*>(routine.release());
+}
+
+void zetasql_Routine_free(void* routine) {
+    delete static_cast<zetasql_Routine*>(routine);
+}
+
+bool zetasql_SimpleCatalog_HasFunction(void* catalog, const char* name) {
+    auto* cat = static_cast<googlesql::SimpleCatalog*>(catalog);
+    const googlesql::Function* function = nullptr;
+    return cat->GetFunction(name, &function).ok() && function != nullptr;
+}
+
+void zetasql_SimpleCatalog_AddRoutine(
+    void* catalog, const char* name, void* routine, zetasql_Status* status) {
+    auto* cat = static_cast<googlesql::SimpleCatalog*>(catalog);
+    auto* r = static_cast<zetasql_Routine*>(routine);
+    // SimpleCatalog aborts on duplicate names, and user functions must not
+    // replace builtins, so check first.
+    if (r->procedure != nullptr) {
+        const googlesql::Procedure* existing = nullptr;
+        if (cat->GetProcedure(name, &existing).ok() && existing != nullptr) {
+            set_status(status, absl::AlreadyExistsError(
+                absl::StrCat("procedure ", name, " already exists")));
+            return;
+        }
+        cat->AddProcedure(name, r->procedure.get());
+        set_status(status, absl::OkStatus());
+        return;
+    }
+    if (r->tvf != nullptr) {
+        const googlesql::TableValuedFunction* existing = nullptr;
+        if (cat->GetTableValuedFunction(name, &existing).ok() && existing != nullptr) {
+            set_status(status, absl::AlreadyExistsError(
+                absl::StrCat("table function ", name, " already exists")));
+            return;
+        }
+        cat->AddTableValuedFunction(name, r->tvf.get());
+        set_status(status, absl::OkStatus());
+        return;
+    }
+    const googlesql::Function* existing = nullptr;
+    if (cat->GetFunction(name, &existing).ok() && existing != nullptr) {
+        set_status(status, absl::AlreadyExistsError(
+            absl::StrCat("function ", name, " already exists")));
+        return;
+    }
+    cat->AddFunction(name, r->function.get());
+    set_status(status, absl::OkStatus());
 }
 
 void* zetasql_SimpleTable_new(
     const char* name,
     zetasql_ColumnDef* columns,
     int column_count,
+    void* catalog,
     void* factory,
+    void* opts,
     zetasql_Status* status) {
+    auto* cat = static_cast<googlesql::Catalog*>(catalog);
     auto* tf = static_cast<googlesql::TypeFactory*>(factory);
+    const auto& options = *static_cast<googlesql::AnalyzerOptions*>(opts);
 
-    std::vector<googlesql::SimpleTable::NameAndType> cols;
+    auto table = std::make_unique<googlesql::SimpleTable>(name);
     for (int i = 0; i < column_count; i++) {
         const googlesql::Type* col_type = nullptr;
-        auto s = parse_type(columns[i].type_name, tf, &col_type);
+        auto s = parse_type(columns[i].type_name, cat, tf, options, &col_type, nullptr);
+        std::string mode = columns[i].mode != nullptr ? columns[i].mode : "";
+        if (s.ok() && mode != "" && mode != "REQUIRED" && mode != "REPEATED") {
+            s = absl::InvalidArgumentError(absl::StrCat("unknown mode ", mode));
+        } else if (s.ok() && mode == "REPEATED" && !col_type->IsArray()) {
+            s = absl::InvalidArgumentError(absl::StrCat(
+                "REPEATED column has type ", col_type->TypeName(googlesql::PRODUCT_EXTERNAL),
+                ", not an ARRAY"));
+        }
+        if (s.ok()) {
+            googlesql::SimpleColumn::Attributes attributes;
+            attributes.is_pseudo_column = columns[i].is_pseudo;
+            s = table->AddColumn(
+                new googlesql::SimpleColumn(name, columns[i].name, col_type, attributes),
+                /*is_owned=*/true);
+        }
         if (!s.ok()) {
-            set_status(status, s);
+            set_status(status, absl::Status(s.code(), absl::StrCat(
+                "column ", columns[i].name, ": ", s.message())));
             return nullptr;
         }
-        cols.push_back({columns[i].name, col_type});
     }
 
-    auto* table = new googlesql::SimpleTable(name, cols);
-
-    status->ok = true;
-    status->error_message = nullptr;
-    status->error_line = 0;
-    status->error_column = 0;
-
-    return static_cast<void*>(table);
+    set_status(status, absl::OkStatus());
+    return static_cast<void*>(table.release());
 }
 
 void zetasql_SimpleTable_free(void* table) {
@@ -249,7 +1036,12 @@ void zetasql_SimpleTable_free(void* table) {
 }
 
 void* zetasql_AnalyzerOptions_new() {
-    return static_cast<void*>(new googlesql::AnalyzerOptions());
+    auto* opts = new googlesql::AnalyzerOptions();
+    // Table scans then list only the columns a statement actually reads,
+    // and resolved nodes carry their source location.
+    opts->set_prune_unused_columns(true);
+    opts->set_parse_location_record_type(googlesql::PARSE_LOCATION_RECORD_FULL_NODE_SCOPE);
+    return static_cast<void*>(opts);
 }
 
 void zetasql_AnalyzerOptions_free(void* opts) {
@@ -261,6 +1053,60 @@ void zetasql_AnalyzerOptions_SetLanguageOptions(void* opts, void* lang_opts) {
         *static_cast<googlesql::LanguageOptions*>(lang_opts));
 }
 
+// parameter_type resolves the type of a query parameter or system variable
+// for opts.
+static absl::Status parameter_type(void* opts, const char* type_name, void* catalog,
+                                   const googlesql::Type** type) {
+    auto* cat = static_cast<googlesql::SimpleCatalog*>(catalog);
+    return parse_type(type_name, cat, cat->type_factory(),
+                      *static_cast<googlesql::AnalyzerOptions*>(opts), type, nullptr);
+}
+
+void zetasql_AnalyzerOptions_AddQueryParameter(
+    void* opts, const char* name, const char* type_name, void* catalog,
+    zetasql_Status* status) {
+    const googlesql::Type* type = nullptr;
+    auto s = parameter_type(opts, type_name, catalog, &type);
+    if (!s.ok()) {
+        set_type_status(status, s, type_name);
+        return;
+    }
+    set_status(status, static_cast<googlesql::AnalyzerOptions*>(opts)->AddQueryParameter(name, type));
+}
+
+void zetasql_AnalyzerOptions_AddPositionalQueryParameter(
+    void* opts, const char* type_name, void* catalog, zetasql_Status* status) {
+    const googlesql::Type* type = nullptr;
+    auto s = parameter_type(opts, type_name, catalog, &type);
+    if (!s.ok()) {
+        set_type_status(status, s, type_name);
+        return;
+    }
+    set_status(status, static_cast<googlesql::AnalyzerOptions*>(opts)->AddPositionalQueryParameter(type));
+}
+
+void zetasql_AnalyzerOptions_SetPositionalParameters(void* opts, bool positional) {
+    static_cast<googlesql::AnalyzerOptions*>(opts)->set_parameter_mode(
+        positional ? googlesql::PARAMETER_POSITIONAL : googlesql::PARAMETER_NAMED);
+}
+
+void zetasql_AnalyzerOptions_SetAllowUndeclaredParameters(void* opts, bool allow) {
+    static_cast<googlesql::AnalyzerOptions*>(opts)->set_allow_undeclared_parameters(allow);
+}
+
+void zetasql_AnalyzerOptions_AddSystemVariable(
+    void* opts, const char* name, const char* type_name, void* catalog,
+    zetasql_Status* status) {
+    const googlesql::Type* type = nullptr;
+    auto s = parameter_type(opts, type_name, catalog, &type);
+    if (!s.ok()) {
+        set_type_status(status, s, type_name);
+        return;
+    }
+    std::vector<std::string> name_path = absl::StrSplit(name, '.');
+    set_status(status, static_cast<googlesql::AnalyzerOptions*>(opts)->AddSystemVariable(name_path, type));
+}
+
 void zetasql_ParseStatement(const char* sql, zetasql_Status* status) {
     googlesql::LanguageOptions lang;
     lang.EnableMaximumLanguageFeatures();
@@ -268,7 +1114,7 @@ void zetasql_ParseStatement(const char* sql, zetasql_Status* status) {
     googlesql::ParserOptions opts(lang);
     std::unique_ptr<googlesql::ParserOutput> output;
     auto s = googlesql::ParseStatement(sql, opts, &output);
-    set_status(status, s);
+    set_parse_status(status, s, sql);
 }
 
 void zetasql_ParseScript(const char* sql, zetasql_Status* status) {
@@ -280,23 +1126,349 @@ void zetasql_ParseScript(const char* sql, zetasql_Status* status) {
     googlesql::ErrorMessageOptions err_opts;
     err_opts.mode = googlesql::ERROR_MESSAGE_WITH_PAYLOAD;
     auto s = googlesql::ParseScript(sql, opts, err_opts, &output);
-    set_status(status, s);
+    set_parse_status(status, s, sql);
+}
+
+void zetasql_ParseScriptAST(
+    const char* sql, zetasql_ASTNode** nodes, int* node_count, zetasql_Status* status) {
+    *nodes = nullptr;
+    *node_count = 0;
+
+    std::unique_ptr<googlesql::ParserOutput> output;
+    googlesql::ErrorMessageOptions err_opts;
+    err_opts.mode = googlesql::ERROR_MESSAGE_WITH_PAYLOAD;
+    auto s = googlesql::ParseScript(sql, script_parser_options(), err_opts, &output);
+    set_parse_status(status, s, sql);
+    if (!s.ok()) return;
+
+    std::vector<zetasql_ASTNode> flat;
+    flatten_ast(output->script(), -1, &flat);
+    copy_nodes(flat, nodes, node_count);
+}
+
+void zetasql_ASTNodes_free(zetasql_ASTNode* nodes, int node_count) {
+    for (int i = 0; i < node_count; i++) {
+        free(nodes[i].kind);
+        free(nodes[i].name);
+    }
+    free(nodes);
+}
+
+void zetasql_ParseNextScriptStatement(
+    const char* sql, int* byte_position, bool* at_end_of_input,
+    zetasql_ASTNode** nodes, int* node_count, zetasql_Status* status) {
+    *nodes = nullptr;
+    *node_count = 0;
+    *at_end_of_input = false;
+
+    // Locations are relative to the whole input, not the resume position.
+    auto location = googlesql::ParseResumeLocation::FromStringView(sql);
+    location.set_byte_position(*byte_position);
+    std::unique_ptr<googlesql::ParserOutput> output;
+    auto s = googlesql::ParseNextScriptStatement(
+        &location, script_parser_options(), &output, at_end_of_input);
+    set_parse_status(status, s, sql);
+    if (!s.ok()) return;
+
+    *byte_position = location.byte_position();
+    std::vector<zetasql_ASTNode> flat;
+    flatten_ast(output->statement(), -1, &flat);
+    copy_nodes(flat, nodes, node_count);
+}
+
+int zetasql_SkipToNextStatement(const char* sql, int byte_position) {
+    int length = static_cast<int>(strlen(sql));
+    auto location = googlesql::ParseResumeLocation::FromStringView(sql);
+    location.set_byte_position(byte_position);
+
+    googlesql::ParseTokenOptions opts;
+    opts.stop_at_end_of_statement = true;
+    std::vector<googlesql::ParseToken> tokens;
+    if (googlesql::GetParseTokens(opts, &location, &tokens).ok() &&
+        location.byte_position() > byte_position) {
+        return location.byte_position();
+    }
+
+    // The tokenizer gives up on malformed input such as an unterminated
+    // string literal; fall back to the next raw semicolon.
+    const char* semi = strchr(sql + byte_position, ';');
+    return semi != nullptr ? static_cast<int>(semi - sql) + 1 : length;
+}
+
+// Reports whether word, an upper-cased token, ends a block statement
+// after END, as in END IF.
+static bool ends_block(const std::string& word) {
+    return word == "IF" || word == "LOOP" || word == "WHILE" ||
+           word == "REPEAT" || word == "FOR" || word == "CASE";
+}
+
+int zetasql_SkipStatement(const char* sql, int byte_position) {
+    auto location = googlesql::ParseResumeLocation::FromStringView(sql);
+    location.set_byte_position(byte_position);
+    std::vector<googlesql::ParseToken> tokens;
+    if (!googlesql::GetParseTokens(googlesql::ParseTokenOptions(), &location, &tokens).ok()) {
+        return -1;
+    }
+
+    std::vector<std::string> words;
+    std::vector<int> ends;
+    for (const auto& token : tokens) {
+        if (token.IsEndOfInput()) break;
+        if (token.IsComment()) continue;
+        auto range = token.GetLocationRange();
+        int start = range.start().GetByteOffset();
+        int end = range.end().GetByteOffset();
+        words.push_back(absl::AsciiStrToUpper(absl::string_view(sql + start, end - start)));
+        ends.push_back(end);
+    }
+
+    // open holds 'b' for each open block statement and 'e' for each open
+    // CASE expression. Block statements only start where a statement can,
+    // except for the BEGIN of a CREATE PROCEDURE body, which follows its
+    // header. create and procedure track that header.
+    std::string open;
+    bool at_statement = true;
+    bool create = false;
+    bool procedure = false;
+    for (size_t i = 0; i < words.size(); i++) {
+        const std::string& word = words[i];
+        const std::string next = i + 1 < words.size() ? words[i + 1] : "";
+        bool starts_statement = at_statement;
+        at_statement = false;
+        if (word == ";") {
+            create = procedure = false;
+            if (open.empty()) return ends[i];
+            at_statement = true;
+        } else if (starts_statement && word == "CREATE") {
+            create = true;
+        } else if (create && word == "PROCEDURE") {
+            procedure = true;
+        } else if (procedure && word == "BEGIN") {
+            create = procedure = false;
+            open.push_back('b');
+            at_statement = true;
+        } else if (starts_statement && next == ":") {
+            i++;  // a label
+            at_statement = true;
+        } else if (starts_statement &&
+                   (ends_block(word) ||
+                    (word == "BEGIN" && next != "TRANSACTION" && next != ";"))) {
+            open.push_back('b');
+            at_statement = word == "BEGIN" || word == "LOOP" || word == "REPEAT";
+        } else if (word == "CASE") {
+            open.push_back('e');
+        } else if (word == "END" && !open.empty()) {
+            if (ends_block(next)) {
+                // END IF and the like close the innermost block along with
+                // any CASE expression left open inside it.
+                auto b = open.rfind('b');
+                open.resize(b == std::string::npos ? 0 : b);
+                i++;
+            } else {
+                open.pop_back();
+            }
+        } else if ((word == "THEN" || word == "ELSE" || word == "DO") &&
+                   (open.empty() || open.back() == 'b')) {
+            at_statement = true;
+        }
+    }
+    return -1;
+}
+
+// A statement parsed by zetasql_ParsedStatement_new. Its parse tree refers
+// to sql, which is kept with it.
+struct ParsedStatement {
+    std::string sql;
+    std::unique_ptr<googlesql::ParserOutput> output;
+};
+
+void* zetasql_ParsedStatement_new(
+    const char* sql, void* opts, zetasql_ASTNode** nodes, int* node_count,
+    zetasql_Status* status) {
+    if (nodes != nullptr) {
+        *nodes = nullptr;
+        *node_count = 0;
+    }
+    const auto& options = *static_cast<googlesql::AnalyzerOptions*>(opts);
+    auto parsed = std::make_unique<ParsedStatement>();
+    parsed->sql = sql;
+    auto s = googlesql::ParseStatement(parsed->sql, options.GetParserOptions(), &parsed->output);
+    set_parse_status(status, s, sql);
+    if (!s.ok()) return nullptr;
+
+    if (nodes != nullptr) {
+        std::vector<zetasql_ASTNode> flat;
+        flatten_ast(parsed->output->statement(), -1, &flat);
+        copy_nodes(flat, nodes, node_count);
+    }
+    return parsed.release();
 }
 
-void zetasql_AnalyzeStatement(
-    const char* sql, void* catalog, void* opts, zetasql_Status* status) {
+void zetasql_ParsedStatement_Analyze(
+    void* parsed, void* catalog, void* factory, void* opts, bool positional,
+    zetasql_AnalyzerOutput* out, zetasql_Status* status) {
+    memset(out, 0, sizeof(*out));
+    auto* p = static_cast<ParsedStatement*>(parsed);
+    const auto* options = static_cast<googlesql::AnalyzerOptions*>(opts);
+
+    // The parameter mode is per statement, so it goes on a copy rather
+    // than on options, which may be shared.
+    auto mode = positional ? googlesql::PARAMETER_POSITIONAL : googlesql::PARAMETER_NAMED;
+    std::optional<googlesql::AnalyzerOptions> copy;
+    if (options->parameter_mode() != mode) {
+        copy.emplace(*options);
+        copy->set_parameter_mode(mode);
+        options = &*copy;
+    }
+
     std::unique_ptr<const googlesql::AnalyzerOutput> output;
-    auto s = googlesql::AnalyzeStatement(
-        sql,
-        *static_cast<googlesql::AnalyzerOptions*>(opts),
-        static_cast<googlesql::SimpleCatalog*>(catalog),
-        static_cast<googlesql::SimpleCatalog*>(catalog)->type_factory(),
+    auto s = googlesql::AnalyzeStatementFromParserAST(
+        *p->output->statement(),
+        *options,
+        p->sql,
+        static_cast<googlesql::Catalog*>(catalog),
+        static_cast<googlesql::TypeFactory*>(factory),
         &output);
+    set_status_for_input(status, s, p->sql.c_str());
+    if (!s.ok()) {
+        status->error_source = classify_error(
+            p->output->statement(), status->error_offset,
+            static_cast<googlesql::Catalog*>(catalog), *options);
+        return;
+    }
+    fill_analyzer_output(*output, out);
+}
+
+void zetasql_ParsedStatement_free(void* parsed) {
+    delete static_cast<ParsedStatement*>(parsed);
+}
+
+void zetasql_AnalyzeExpression(
+    const char* sql, void* catalog, void* factory, void* opts,
+    const char* target_type, char** type_name, zetasql_Status* status) {
+    *type_name = nullptr;
+    auto* tf = static_cast<googlesql::TypeFactory*>(factory);
+    const auto& options = *static_cast<googlesql::AnalyzerOptions*>(opts);
+    auto* cat = static_cast<googlesql::Catalog*>(catalog);
+
+    std::unique_ptr<const googlesql::AnalyzerOutput> output;
+    absl::Status s;
+    if (target_type != nullptr) {
+        const googlesql::Type* target = nullptr;
+        s = parse_type(target_type, cat, tf, options, &target, nullptr);
+        if (!s.ok()) {
+            set_type_status(status, s, target_type);
+            return;
+        }
+        s = googlesql::AnalyzeExpressionForAssignmentToType(
+            sql, options, cat, tf, target, &output);
+    } else {
+        s = googlesql::AnalyzeExpression(sql, options, cat, tf, &output);
+    }
+    set_status_for_input(status, s, sql);
+    if (!s.ok()) {
+        std::unique_ptr<googlesql::ParserOutput> parsed;
+        if (!googlesql::ParseExpression(sql, options.GetParserOptions(), &parsed).ok()) {
+            status->error_source = ZETASQL_ERROR_SYNTAX;
+            return;
+        }
+        status->error_source = classify_error(parsed->expression(), status->error_offset, cat, options);
+        // Short of anything more specific, an assignment fails because the
+        // value does not coerce to the target type.
+        if (status->error_source == ZETASQL_ERROR_OTHER && target_type != nullptr) {
+            status->error_source = ZETASQL_ERROR_TYPE_MISMATCH;
+        }
+        return;
+    }
+    *type_name = dup_string(
+        output->resolved_expr()->type()->TypeName(googlesql::PRODUCT_EXTERNAL));
+}
+
+void zetasql_ParseType(
+    const char* type_name, void* catalog, void* factory, void* opts,
+    char** normalized, zetasql_Status* status) {
+    *normalized = nullptr;
+    const googlesql::Type* type = nullptr;
+    googlesql::TypeParameters params;
+    auto s = parse_type(type_name, static_cast<googlesql::Catalog*>(catalog),
+                        static_cast<googlesql::TypeFactory*>(factory),
+                        *static_cast<googlesql::AnalyzerOptions*>(opts), &type, &params);
+    if (!s.ok()) {
+        set_type_status(status, s, type_name);
+        return;
+    }
+    auto name = type->TypeNameWithParameters(params, googlesql::PRODUCT_EXTERNAL);
+    if (!name.ok()) {
+        set_type_status(status, name.status(), type_name);
+        return;
+    }
     set_status(status, s);
+    *normalized = dup_string(*name);
+}
+
+void zetasql_AnalyzerOutput_free(zetasql_AnalyzerOutput* out) {
+    free(out->statement_kind);
+    for (int i = 0; i < out->output_column_count; i++) {
+        free(out->output_columns[i].name);
+        free(out->output_columns[i].type_name);
+        free(out->output_columns[i].mode);
+    }
+    free(out->output_columns);
+    free_strings(out->tables, out->table_count);
+    for (int i = 0; i < out->column_count; i++) {
+        free(out->columns[i].table);
+        free(out->columns[i].column);
+    }
+    free(out->columns);
+    free_strings(out->warnings, out->warning_count);
+    zetasql_ASTNodes_free(out->resolved_nodes, out->resolved_node_count);
+    for (int i = 0; i < out->undeclared_parameter_count; i++) {
+        free(out->undeclared_parameters[i].name);
+        free(out->undeclared_parameters[i].type_name);
+        free(out->undeclared_parameters[i].mode);
+    }
+    free(out->undeclared_parameters);
+    free_strings(out->name_path, out->name_path_count);
+    for (int i = 0; i < out->column_definition_count; i++) {
+        free(out->column_definitions[i].name);
+        free(out->column_definitions[i].type_name);
+        free(out->column_definitions[i].mode);
+    }
+    free(out->column_definitions);
+    free(out->object_type);
+    for (int i = 0; i < out->alter_action_count; i++) {
+        free(out->alter_actions[i].kind);
+        free(out->alter_actions[i].column);
+        free(out->alter_actions[i].new_name);
+        free(out->alter_actions[i].type_name);
+        free(out->alter_actions[i].mode);
+    }
+    free(out->alter_actions);
+    for (int i = 0; i < out->argument_count; i++) {
+        free(out->arguments[i].name);
+        free(out->arguments[i].type_name);
+        free(out->arguments[i].mode);
+    }
+    free(out->arguments);
+    free(out->body);
+    memset(out, 0, sizeof(*out));
+}
+
+void zetasql_Keywords(char*** keywords, int* count) {
+    std::vector<std::string> out;
+    for (const auto& info : googlesql::parser::GetAllKeywords()) {
+        out.push_back(absl::AsciiStrToUpper(info.keyword()));
+    }
+    *keywords = dup_strings(out);
+    *count = static_cast<int>(out.size());
 }
 
 void zetasql_free_string(char* s) {
     free(s);
 }
 
+void zetasql_free_strings(char** strs, int count) {
+    free_strings(strs, count);
+}
+
 } // extern "C"
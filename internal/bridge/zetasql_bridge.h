@@ -14,14 +14,107 @@ typedef struct {
     char* error_message;      // Caller must free with zetasql_free_string
     int error_line;           // 1-based line, 0 if not available
     int error_column;         // 1-based column, 0 if not available
+    int error_offset;         // 0-based byte offset into the input, -1 if not available
+    int error_code;           // absl::StatusCode, 0 (OK) on success
+    int error_source;         // What the error is about, a ZETASQL_ERROR_* value
 } zetasql_Status;
 
+// Error sources. Analysis errors are classified by the parse tree node at
+// their location, syntax errors by having come from the parser.
+#define ZETASQL_ERROR_OTHER              0
+#define ZETASQL_ERROR_SYNTAX             1
+#define ZETASQL_ERROR_UNKNOWN_TABLE      2
+#define ZETASQL_ERROR_UNKNOWN_COLUMN     3  // also an unknown field
+#define ZETASQL_ERROR_UNKNOWN_FUNCTION   4  // also table functions and procedures
+#define ZETASQL_ERROR_UNKNOWN_TYPE       5
+#define ZETASQL_ERROR_UNKNOWN_PARAMETER  6
+#define ZETASQL_ERROR_TYPE_MISMATCH      7
+#define ZETASQL_ERROR_SIGNATURE_MISMATCH 8
+
 // Column info for creating tables
 typedef struct {
     const char* name;
     const char* type_name;    // e.g. "INT64", "STRING", "ARRAY<STRING>", "STRUCT<a INT64, b STRING>"
+    const char* mode;         // "" (NULLABLE), "REQUIRED" or "REPEATED", which needs an ARRAY type
+    bool is_pseudo;           // Pseudo-column, e.g. _PARTITIONTIME: selectable by name, not by SELECT *
 } zetasql_ColumnDef;
 
+// Parse tree node, flattened in pre-order
+typedef struct {
+    char* kind;               // Node kind without the AST prefix, e.g. "QueryStatement"
+    char* name;               // Identifier value or literal image, NULL otherwise
+    int parent;               // Index of the parent node, -1 for the root
+    int start;                // Byte offset of the first character
+    int end;                  // Byte offset one past the last character
+    int flags;                // Bitwise OR of ZETASQL_AST_* flags
+} zetasql_ASTNode;
+
+#define ZETASQL_AST_STATEMENT        1
+#define ZETASQL_AST_SCRIPT_STATEMENT 2
+#define ZETASQL_AST_EXPRESSION       4
+#define ZETASQL_AST_QUERY_EXPRESSION 8
+#define ZETASQL_AST_TABLE_EXPRESSION 16
+#define ZETASQL_AST_TYPE             32
+
+// Column name and type as reported by the analyzer
+typedef struct {
+    char* name;
+    char* type_name;
+    char* mode;               // "REQUIRED" for a NOT NULL column definition, NULL otherwise
+} zetasql_NameAndType;
+
+// Column read from a table
+typedef struct {
+    char* table;              // Full table name
+    char* column;
+} zetasql_ColumnRef;
+
+// One action of an ALTER TABLE statement
+typedef struct {
+    char* kind;               // Resolved node kind, e.g. "AddColumnAction"
+    char* column;             // Column the action applies to
+    char* new_name;           // New column name for RENAME COLUMN, NULL otherwise
+    char* type_name;          // Column type for ADD COLUMN / SET DATA TYPE, NULL otherwise
+    char* mode;               // "REQUIRED" for an added NOT NULL column, NULL otherwise
+    bool if_exists;           // ADD COLUMN IF NOT EXISTS or DROP COLUMN IF EXISTS
+} zetasql_AlterAction;
+
+// One argument of a CREATE PROCEDURE statement
+typedef struct {
+    char* name;
+    char* type_name;          // "ANY TYPE" for a templated argument
+    char* mode;               // "IN", "OUT" or "INOUT"
+} zetasql_Argument;
+
+// Result of a successful analysis. Free with zetasql_AnalyzerOutput_free.
+typedef struct {
+    char* statement_kind;     // Resolved node kind, e.g. "QueryStmt"
+    zetasql_NameAndType* output_columns;
+    int output_column_count;
+    char** tables;            // Full names of referenced tables
+    int table_count;
+    zetasql_ColumnRef* columns;
+    int column_count;
+    char** warnings;
+    int warning_count;
+    zetasql_ASTNode* resolved_nodes;  // Resolved AST in pre-order, start/end -1 if unknown
+    int resolved_node_count;
+    zetasql_NameAndType* undeclared_parameters;  // Inferred types of parameters that were not added;
+    int undeclared_parameter_count;              // named ones by name, then positional ones (unnamed) in order
+
+    // DDL statements only
+    char** name_path;         // Name of the object created, dropped, altered or called
+    int name_path_count;
+    zetasql_NameAndType* column_definitions;  // Columns of a created table or view
+    int column_definition_count;
+    char* object_type;        // Object type of a DROP statement, e.g. "TABLE"
+    zetasql_AlterAction* alter_actions;
+    int alter_action_count;
+    zetasql_Argument* arguments;   // Arguments of a created procedure
+    int argument_count;
+    char* body;               // Body of a created procedure, NULL otherwise
+} zetasql_AnalyzerOutput;
+
 // All "new" functions return opaque void* handles.
 // All "free" functions take a void* handle.
 // All "method" functions take a void* handle as first arg.
@@ -43,14 +136,62 @@ void zetasql_SimpleCatalog_free(void* catalog);
 void zetasql_SimpleCatalog_AddBuiltinFunctionsAndTypes(
     void* catalog, void* lang_opts, zetasql_Status* status);
 void* zetasql_SimpleCatalog_AddSubCatalog(void* catalog, const char* name);
-void zetasql_SimpleCatalog_AddTable(void* catalog, void* table);
+// Adds a table under the given name. When owned is true the catalog takes
+// ownership of the table; otherwise the table is an alias of one owned by
+// another catalog in the same tree, which must outlive this one's use of it.
+void zetasql_SimpleCatalog_AddTable(
+    void* catalog, const char* name, void* table, bool owned);
+// Adds a named constant holding a NULL of the given type. Script variables
+// are modeled as constants so that bare identifiers resolve to them.
+void zetasql_SimpleCatalog_AddConstant(
+    void* catalog, const char* name, const char* type_name, void* opts,
+    zetasql_Status* status);
+// Returns the catalog as a googlesql::Catalog* for analysis.
+void* zetasql_SimpleCatalog_AsCatalog(void* catalog);
+// Returns the names of the functions and table-valued functions added to
+// the catalog itself, not to its sub-catalogs, in *names, to be freed with
+// zetasql_free_strings.
+void zetasql_SimpleCatalog_FunctionNames(void* catalog, char*** names, int* count);
+
+// --- MultiCatalog ---
+// Looks up names in each catalog in order. The catalogs are googlesql::Catalog*
+// handles and must outlive the MultiCatalog. Returns a googlesql::Catalog*.
+void* zetasql_MultiCatalog_new(
+    const char* name, void** catalogs, int catalog_count, zetasql_Status* status);
+void zetasql_MultiCatalog_free(void* catalog);
+
+// --- Routine ---
+// Analyzes a CREATE FUNCTION, CREATE TABLE FUNCTION or CREATE PROCEDURE
+// statement against catalog (a googlesql::Catalog*), type-checking SQL
+// function bodies, and returns the routine it defines. Free with
+// zetasql_Routine_free only after every catalog the routine was added to.
+// On success *output describes the statement as
+// zetasql_ParsedStatement_Analyze would and must be released with
+// zetasql_AnalyzerOutput_free.
+void* zetasql_Routine_new(
+    const char* sql, void* catalog, void* factory, void* opts,
+    zetasql_AnalyzerOutput* output, zetasql_Status* status);
+void zetasql_Routine_free(void* routine);
+// Adds a routine under the given name without taking ownership. Fails if a
+// routine of the same kind (function, table function or procedure) and
+// name, builtin or not, already exists.
+void zetasql_SimpleCatalog_AddRoutine(
+    void* catalog, const char* name, void* routine, zetasql_Status* status);
+// Reports whether the catalog itself, not counting sub-catalogs, has a
+// function of the given name, builtin or not.
+bool zetasql_SimpleCatalog_HasFunction(void* catalog, const char* name);
 
 // --- SimpleTable ---
+// Column types are resolved like types in a CREATE TABLE column list, using
+// the googlesql::Catalog* for named types and opts for language features.
+// Duplicate column names are an error.
 void* zetasql_SimpleTable_new(
     const char* name,
     zetasql_ColumnDef* columns,
     int column_count,
+    void* catalog,
     void* factory,
+    void* opts,
     zetasql_Status* status);
 void zetasql_SimpleTable_free(void* table);
 
@@ -58,17 +199,92 @@ void zetasql_SimpleTable_free(void* table);
 void* zetasql_AnalyzerOptions_new();
 void zetasql_AnalyzerOptions_free(void* opts);
 void zetasql_AnalyzerOptions_SetLanguageOptions(void* opts, void* lang_opts);
+// Query parameter types are resolved like column types, using catalog (a
+// googlesql::SimpleCatalog*) for named types and its type factory, which
+// must outlive opts.
+void zetasql_AnalyzerOptions_AddQueryParameter(
+    void* opts, const char* name, const char* type_name, void* catalog,
+    zetasql_Status* status);
+// Adds the next positional (?) parameter.
+void zetasql_AnalyzerOptions_AddPositionalQueryParameter(
+    void* opts, const char* type_name, void* catalog, zetasql_Status* status);
+// Selects positional (?) rather than named (@name) parameters, the default.
+void zetasql_AnalyzerOptions_SetPositionalParameters(void* opts, bool positional);
+// Lets statements use parameters that were not added. Their types are
+// inferred from their use and reported in undeclared_parameters.
+void zetasql_AnalyzerOptions_SetAllowUndeclaredParameters(void* opts, bool allow);
+// Declares the system variable @@name, where name is a dotted path such as
+// "script.job_id". Its type is resolved as for query parameters.
+void zetasql_AnalyzerOptions_AddSystemVariable(
+    void* opts, const char* name, const char* type_name, void* catalog,
+    zetasql_Status* status);
 
 // --- Parse ---
 void zetasql_ParseStatement(const char* sql, zetasql_Status* status);
 void zetasql_ParseScript(const char* sql, zetasql_Status* status);
+// Parses a script and returns its parse tree. On success *nodes must be
+// freed with zetasql_ASTNodes_free.
+void zetasql_ParseScriptAST(
+    const char* sql, zetasql_ASTNode** nodes, int* node_count, zetasql_Status* status);
+void zetasql_ASTNodes_free(zetasql_ASTNode* nodes, int node_count);
+
+// Parses the next statement of a script starting at *byte_position, which is
+// advanced past the statement. On success *nodes holds the statement's parse
+// tree and must be freed with zetasql_ASTNodes_free.
+void zetasql_ParseNextScriptStatement(
+    const char* sql, int* byte_position, bool* at_end_of_input,
+    zetasql_ASTNode** nodes, int* node_count, zetasql_Status* status);
+
+// Returns the byte offset just past the next ';' at or after byte_position,
+// skipping string literals and comments, or the input length if there is none.
+int zetasql_SkipToNextStatement(const char* sql, int byte_position);
+// Returns the byte offset just past the ';' that ends the statement starting
+// at byte_position, counting the statements nested in its BEGIN, IF, LOOP,
+// WHILE, REPEAT, FOR and CASE blocks as part of it. Returns -1 if its
+// blocks are still open at the end of the input or the input cannot be
+// tokenized.
+int zetasql_SkipStatement(const char* sql, int byte_position);
 
 // --- Analyze ---
-void zetasql_AnalyzeStatement(
-    const char* sql, void* catalog, void* opts, zetasql_Status* status);
+// Parses a statement for analysis, with the parser options implied by
+// opts, so that it can be inspected and then analyzed without being
+// parsed again. If nodes is not NULL, on success *nodes holds
+// the statement's parse tree and must be freed with zetasql_ASTNodes_free.
+// Returns a handle to free with zetasql_ParsedStatement_free, or NULL.
+void* zetasql_ParsedStatement_new(
+    const char* sql, void* opts, zetasql_ASTNode** nodes, int* node_count,
+    zetasql_Status* status);
+// Analyzes a parsed statement against catalog, a googlesql::Catalog*
+// handle, with positional (?) parameters if positional is set and named
+// (@name) ones otherwise, whatever mode opts selects. opts is not
+// modified. On success *output is filled and must be released with
+// zetasql_AnalyzerOutput_free.
+void zetasql_ParsedStatement_Analyze(
+    void* parsed, void* catalog, void* factory, void* opts, bool positional,
+    zetasql_AnalyzerOutput* output, zetasql_Status* status);
+void zetasql_ParsedStatement_free(void* parsed);
+// Analyzes a standalone expression and returns its type name in *type_name,
+// to be freed with zetasql_free_string. If target_type is not NULL, the
+// expression must be assignable (coercible) to that type.
+void zetasql_AnalyzeExpression(
+    const char* sql, void* catalog, void* factory, void* opts,
+    const char* target_type, char** type_name, zetasql_Status* status);
+// Resolves a type string, such as a column type in a schema file, and
+// returns its canonical spelling with any type parameters in *normalized,
+// to be freed with zetasql_free_string. Error locations are relative to
+// type_name.
+void zetasql_ParseType(
+    const char* type_name, void* catalog, void* factory, void* opts,
+    char** normalized, zetasql_Status* status);
+void zetasql_AnalyzerOutput_free(zetasql_AnalyzerOutput* output);
+
+// Returns the GoogleSQL keywords, reserved and not, in upper case in
+// *keywords, to be freed with zetasql_free_strings.
+void zetasql_Keywords(char*** keywords, int* count);
 
 // --- Utility ---
 void zetasql_free_string(char* s);
+void zetasql_free_strings(char** strs, int count);
 
 #ifdef __cplusplus
 }
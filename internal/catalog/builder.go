@@ -2,71 +2,106 @@
 package catalog
 
 import (
-	"strings"
+	"errors"
 
 	"github.com/pacer/go-bigq/bigq"
 	"github.com/pacer/go-bigq/internal/schema"
 )
 
 // BuildFromSchema creates a Catalog from a schema definition.
-// Tables with qualified names (project.dataset.table) get nested sub-catalogs.
-func BuildFromSchema(s *schema.Schema) (*bigq.Catalog, error) {
-	cat, err := bigq.NewCatalog("root")
+// Tables with qualified names (project.dataset.table) are nested in project
+// and dataset sub-catalogs. Procedure bodies are not checked; see
+// AddSchema.
+func BuildFromSchema(s *schema.Schema, opts ...bigq.CatalogOption) (*bigq.Catalog, error) {
+	cat, err := bigq.NewCatalog("root", opts...)
 	if err != nil {
 		return nil, err
 	}
+	if err := AddSchema(cat, s, nil); err != nil {
+		cat.Close()
+		return nil, err
+	}
+	return cat, nil
+}
 
+// AddSchema adds the tables of a schema definition to cat, so that several
+// schema sources can be loaded into one catalog. Routines are added after
+// the tables, then views, in dependency order, with the columns their
+// queries produce, and then the routines that read views. Procedure bodies
+// are then checked against the complete catalog with check, if not nil.
+// The returned error lists every routine or view that no longer analyzes.
+func AddSchema(cat *bigq.Catalog, s *schema.Schema, check ProcedureChecker) error {
+	var views []schema.Table
 	for _, table := range s.Tables {
+		if table.IsView() {
+			views = append(views, table)
+			continue
+		}
 		columns := make([]bigq.ColumnDef, len(table.Columns))
 		for i, col := range table.Columns {
 			columns[i] = bigq.ColumnDef{
 				Name:     col.Name,
 				TypeName: col.Type,
+				Mode:     col.Mode,
 			}
 		}
+		for _, col := range table.PseudoColumns() {
+			columns = append(columns, bigq.ColumnDef{
+				Name:     col.Name,
+				TypeName: col.Type,
+				Pseudo:   true,
+			})
+		}
 
-		// Split qualified name: project.dataset.table
-		parts := strings.Split(table.Name, ".")
-		if len(parts) == 1 {
-			// Unqualified table name - add directly to root catalog
-			if err := cat.AddTable(parts[0], columns); err != nil {
-				return nil, err
-			}
-		} else {
-			// Create sub-catalogs for each prefix, then add table at the leaf
-			// e.g. "project.dataset.table" -> sub("project").sub("dataset").addTable("table")
-			current := cat
-			for _, part := range parts[:len(parts)-1] {
-				sub := current.AddSubCatalog(part)
-				// Wrap SubCatalog to satisfy our interface
-				// For now, just add the table to the last subcatalog directly
-				_ = sub
-			}
-			// TODO: We need to restructure to support nested sub-catalogs properly.
-			// For now, add table with fully qualified name to root catalog.
-			if err := cat.AddTable(table.Name, columns); err != nil {
-				return nil, err
-			}
+		if err := cat.AddTable(table.Name, columns); err != nil {
+			return err
 		}
 	}
+	// A view may call a routine and a routine may read a view, so the
+	// routines that fail before the views are retried after them.
+	pending, _ := addRoutines(cat, s.Routines)
+	viewErr := addViews(cat, views)
+	if _, err := addRoutines(cat, pending); err != nil || viewErr != nil {
+		return errors.Join(viewErr, err)
+	}
+	return checkProcedures(cat, s.Routines, check)
+}
 
-	return cat, nil
+// addInAnyOrder calls add for each item. Items that fail, e.g. because
+// they refer to one that comes later, are retried until a pass makes no
+// progress; the items still failing then are returned with their errors.
+func addInAnyOrder[T any](items []T, add func(T) error) ([]T, error) {
+	for len(items) > 0 {
+		var failed []T
+		var errs []error
+		for _, item := range items {
+			if err := add(item); err != nil {
+				failed = append(failed, item)
+				errs = append(errs, err)
+			}
+		}
+		if len(failed) == len(items) {
+			return failed, errors.Join(errs...)
+		}
+		items = failed
+	}
+	return nil, nil
 }
 
 // BuildFromFile creates a Catalog from a schema JSON file.
-func BuildFromFile(path string) (*bigq.Catalog, error) {
+func BuildFromFile(path string, opts ...bigq.CatalogOption) (*bigq.Catalog, error) {
 	s, err := schema.LoadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	return BuildFromSchema(s)
+	return BuildFromSchema(s, opts...)
 }
 
 // BuildFromDir creates a Catalog from all JSON schema files in a directory.
-func BuildFromDir(dir string) (*bigq.Catalog, error) {
+func BuildFromDir(dir string, opts ...bigq.CatalogOption) (*bigq.Catalog, error) {
 	s, err := schema.LoadDir(dir)
 	if err != nil {
 		return nil, err
 	}
-	return BuildFromSchema(s)
+	return BuildFromSchema(s, opts...)
 }
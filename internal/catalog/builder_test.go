@@ -0,0 +1,74 @@
+package catalog
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pacer/go-bigq/bigq"
+	"github.com/pacer/go-bigq/internal/schema"
+)
+
+func TestBuildFromSchemaPseudoColumns(t *testing.T) {
+	event := []schema.Column{{Name: "user_id", Type: "INT64"}}
+	s := &schema.Schema{Tables: []schema.Table{
+		{
+			Name:             "logs.requests",
+			Columns:          []schema.Column{{Name: "path", Type: "STRING"}},
+			TimePartitioning: &schema.TimePartitioning{Type: "DAY"},
+		},
+		{Name: "analytics.events_20240101", Columns: event},
+		{Name: "analytics.events_20240102", Columns: append(event, schema.Column{Name: "country", Type: "STRING"})},
+	}}
+
+	cat, err := BuildFromSchema(s)
+	if err != nil {
+		t.Fatalf("BuildFromSchema: %v", err)
+	}
+	defer cat.Close()
+
+	for _, sql := range []string{
+		"SELECT path FROM logs.requests WHERE _PARTITIONDATE = CURRENT_DATE()",
+		"SELECT path FROM logs.requests WHERE _PARTITIONTIME > TIMESTAMP '2024-01-01'",
+		"SELECT user_id, country FROM `analytics.events_*` WHERE _TABLE_SUFFIX >= '20240101'",
+	} {
+		if _, err := bigq.AnalyzeStatement(sql, cat); err != nil {
+			t.Errorf("AnalyzeStatement(%q): %v", sql, err)
+		}
+	}
+}
+
+func TestBuildFromSchemaColumnModes(t *testing.T) {
+	s := &schema.Schema{Tables: []schema.Table{{
+		Name: "sales.orders",
+		Columns: []schema.Column{
+			{Name: "id", Type: "INT64", Mode: "REQUIRED"},
+			{Name: "tags", Type: "ARRAY<STRING>", Mode: "REPEATED"},
+			{Name: "labels", Type: "STRING", Mode: "repeated"},
+			{Name: "note", Type: "STRING", Mode: "NULLABLE"},
+		},
+	}}}
+
+	cat, err := BuildFromSchema(s)
+	if err != nil {
+		t.Fatalf("BuildFromSchema: %v", err)
+	}
+	defer cat.Close()
+
+	columns, ok := cat.Table("sales.orders")
+	if !ok || len(columns) != 4 {
+		t.Fatalf("Table(sales.orders) = %+v, %v", columns, ok)
+	}
+	want := []bigq.ColumnDef{
+		{Name: "id", TypeName: "INT64", Mode: "REQUIRED"},
+		{Name: "tags", TypeName: "ARRAY<STRING>", Mode: "REPEATED"},
+		{Name: "labels", TypeName: "ARRAY<STRING>", Mode: "REPEATED"},
+		{Name: "note", TypeName: "STRING"},
+	}
+	if !slices.Equal(columns, want) {
+		t.Errorf("columns = %+v, want %+v", columns, want)
+	}
+	// A REPEATED column declared with its element type is an array.
+	if _, err := bigq.AnalyzeStatement("SELECT label FROM sales.orders, UNNEST(labels) AS label", cat); err != nil {
+		t.Errorf("AnalyzeStatement: %v", err)
+	}
+}
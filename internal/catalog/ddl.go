@@ -0,0 +1,183 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pacer/go-bigq/bigq"
+	"github.com/pacer/go-bigq/internal/bridge"
+)
+
+// ddlStatement is one statement of a DDL file.
+type ddlStatement struct {
+	file  string
+	sql   string // the whole file
+	start int    // byte offset of the statement in sql
+	text  string
+	kind  string // parse tree node kind, e.g. "CreateTableStatement"
+}
+
+// BuildFromDDL creates a Catalog from CREATE TABLE, CREATE VIEW, CREATE
+// FUNCTION, CREATE TABLE FUNCTION and CREATE PROCEDURE statements in a .sql
+// file or in the .sql files under a directory. Procedure bodies are not
+// checked; see AddDDL.
+func BuildFromDDL(path string, opts ...bigq.CatalogOption) (*bigq.Catalog, error) {
+	cat, err := bigq.NewCatalog("root", opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := AddDDL(cat, path, nil); err != nil {
+		cat.Close()
+		return nil, err
+	}
+	return cat, nil
+}
+
+// AddDDL adds the tables and views defined by the DDL in a .sql file, or in
+// the .sql files under a directory, to cat. CREATE TABLE (including
+// CREATE TABLE ... AS SELECT and CREATE EXTERNAL TABLE), CREATE VIEW and
+// CREATE MATERIALIZED VIEW statements define tables; CREATE FUNCTION,
+// CREATE TABLE FUNCTION and CREATE PROCEDURE statements define routines;
+// other statements are ignored. A statement may refer to tables and
+// routines defined in any of the files, whatever order they are read in.
+// Procedure bodies are checked with check, if not nil, once everything is
+// loaded.
+func AddDDL(cat *bigq.Catalog, path string, check ProcedureChecker) error {
+	files, err := ddlFiles(path)
+	if err != nil {
+		return err
+	}
+
+	var pending []ddlStatement
+	for _, file := range files {
+		stmts, err := parseDDLFile(file)
+		if err != nil {
+			return err
+		}
+		pending = append(pending, stmts...)
+	}
+
+	// Statements that read other tables can only be analyzed once those
+	// tables exist, hence the retries. What fails in the end is a genuine
+	// error.
+	var procedures []ddlStatement
+	_, err = addInAnyOrder(pending, func(stmt ddlStatement) error {
+		if err := addDDLStatement(cat, stmt); err != nil {
+			return stmt.errorf(err)
+		}
+		if stmt.kind == "CreateProcedureStatement" {
+			procedures = append(procedures, stmt)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Procedure bodies may refer to anything in the DDL, so they are
+	// checked last.
+	if check == nil {
+		return nil
+	}
+	var errs []error
+	for _, stmt := range procedures {
+		for _, e := range check(cat, stmt.text) {
+			errs = append(errs, stmt.errorAt(stmt.start+e.Offset, errors.New(e.Message)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ddlFiles returns path itself if it is a file, or the .sql files under it
+// if it is a directory.
+func ddlFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading DDL %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(p) == ".sql" {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading DDL directory %s: %w", path, err)
+	}
+	return files, nil
+}
+
+// parseDDLFile splits a DDL file into statements, failing on the first
+// syntax error.
+func parseDDLFile(file string) ([]ddlStatement, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading DDL file %s: %w", file, err)
+	}
+	sql := string(data)
+
+	var stmts []ddlStatement
+	for _, s := range bigq.ParseScriptStatements(sql) {
+		stmt := ddlStatement{file: file, sql: sql, start: s.Start, text: sql[s.Start:s.End]}
+		if s.Node != nil {
+			stmt.kind = s.Node.Kind
+		}
+		if s.Err != nil {
+			offset := s.ErrOffset
+			if offset < 0 {
+				offset = s.Start
+			}
+			return nil, stmt.errorAt(offset, s.Err)
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, nil
+}
+
+// addDDLStatement analyzes a statement and adds the table, view or routine
+// it defines to cat. Routines are analyzed by AddRoutine alone.
+func addDDLStatement(cat *bigq.Catalog, stmt ddlStatement) error {
+	switch stmt.kind {
+	case "CreateFunctionStatement", "CreateTableFunctionStatement", "CreateProcedureStatement":
+		return cat.AddRoutine(stmt.text)
+	}
+	out, err := bigq.AnalyzeStatement(stmt.text, cat)
+	if err != nil {
+		return err
+	}
+	switch out.StatementKind {
+	case "CreateTableStmt", "CreateTableAsSelectStmt", "CreateExternalTableStmt",
+		"CreateViewStmt", "CreateMaterializedViewStmt":
+		return cat.AddTable(strings.Join(out.NamePath, "."), out.ColumnDefinitions)
+	}
+	return nil
+}
+
+// errorf prefixes an analysis error with the file position it refers to:
+// the error location if it has one, else the start of the statement.
+func (s ddlStatement) errorf(err error) error {
+	offset := s.start
+	var status bridge.Status
+	if errors.As(err, &status) && status.ErrorOffset >= 0 {
+		offset += status.ErrorOffset
+	}
+	return s.errorAt(offset, err)
+}
+
+// errorAt prefixes err with the file position of a byte offset in the file.
+func (s ddlStatement) errorAt(offset int, err error) error {
+	line, col := bigq.Position(s.sql, offset)
+	return fmt.Errorf("%s:%d:%d: %w", s.file, line, col, err)
+}
@@ -0,0 +1,112 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pacer/go-bigq/bigq"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBuildFromDDL(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		// Views are read before the table they select from.
+		"a_views.sql": `
+CREATE VIEW sales.big_orders AS
+SELECT id, customer.name AS customer_name FROM sales.orders WHERE total > 100;`,
+		"sales/orders.sql": `
+CREATE TABLE sales.orders (
+  id INT64 NOT NULL OPTIONS (description = 'Order ID'),
+  total NUMERIC(10, 2),
+  created_at TIMESTAMP,
+  customer STRUCT<name STRING, tags ARRAY<STRING>>,
+  items ARRAY<STRUCT<sku STRING, qty INT64>>
+)
+PARTITION BY DATE(created_at)
+CLUSTER BY id
+OPTIONS (description = 'Orders');
+
+CREATE TABLE sales.daily AS
+SELECT DATE(created_at) AS day, SUM(total) AS total FROM sales.orders GROUP BY day;`,
+		"functions.sql": `
+CREATE FUNCTION sales.net(total NUMERIC) AS (total / 1.2);
+
+CREATE TABLE FUNCTION sales.orders_since(d DATE) AS
+SELECT id, total FROM sales.orders WHERE DATE(created_at) >= d;`,
+		"procedures.sql": `
+CREATE PROCEDURE sales.order_count(OUT n INT64)
+BEGIN
+  SET n = (SELECT COUNT(*) FROM sales.orders);
+END;`,
+		"notes.txt": "not DDL",
+	})
+
+	cat, err := BuildFromDDL(dir)
+	if err != nil {
+		t.Fatalf("BuildFromDDL: %v", err)
+	}
+	defer cat.Close()
+
+	for _, sql := range []string{
+		"SELECT id, total, customer.tags, items[OFFSET(0)].sku FROM sales.orders",
+		"SELECT customer_name FROM sales.big_orders",
+		"SELECT day, total FROM `sales.daily`",
+		"SELECT sales.net(total) FROM sales.orders",
+		"SELECT id, total FROM sales.orders_since(DATE '2024-01-01')",
+		"CALL sales.order_count(NULL)",
+	} {
+		if _, err := bigq.AnalyzeStatement(sql, cat); err != nil {
+			t.Errorf("AnalyzeStatement(%q): %v", sql, err)
+		}
+	}
+
+	columns, _ := cat.Table("sales.orders")
+	if len(columns) < 2 || !columns[0].Required() || columns[1].Required() {
+		t.Errorf("sales.orders columns = %+v, want id REQUIRED and total not", columns)
+	}
+}
+
+func TestBuildFromDDLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{"syntax error", "CREATE TABLE t (id INT64);\nCREATE TABLE u (id INT64,);", "bad.sql:2:"},
+		{"unknown table", "CREATE VIEW v AS\nSELECT id FROM missing;", "bad.sql:2:16:"},
+		{"duplicate table", "CREATE TABLE t (id INT64);\nCREATE TABLE t (id INT64);", "bad.sql:2:1:"},
+		{"procedure body", "CREATE TABLE t (id INT64);\nCREATE PROCEDURE p()\nBEGIN\n  SELECT missing FROM t;\nEND;", "bad.sql:4:10:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bad.sql")
+			if err := os.WriteFile(path, []byte(tt.sql), 0644); err != nil {
+				t.Fatal(err)
+			}
+			cat, err := bigq.NewCatalog("root")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cat.Close()
+			err = AddDDL(cat, path, checkProcedure)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("AddDDL error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pacer/go-bigq/bigq"
+	"github.com/pacer/go-bigq/internal/schema"
+)
+
+// ProcedureChecker lints a CREATE PROCEDURE statement, body included,
+// against cat and returns the errors found. The analyzer leaves procedure
+// bodies alone and linting scripts is beyond this package, so callers that
+// want the bodies checked supply one, typically built on the lint package.
+type ProcedureChecker func(cat *bigq.Catalog, sql string) []ProcedureError
+
+// ProcedureError is an error found by a ProcedureChecker.
+type ProcedureError struct {
+	Offset  int // byte offset in the CREATE PROCEDURE statement
+	Message string
+}
+
+// addRoutines adds schema routines to cat. A routine may call one defined
+// later in the schema. The routines that fail are returned with the errors.
+func addRoutines(cat *bigq.Catalog, routines []schema.Routine) ([]schema.Routine, error) {
+	return addInAnyOrder(routines, func(r schema.Routine) error {
+		if err := cat.AddRoutine(routineDDL(r)); err != nil {
+			return fmt.Errorf("routine %s: %w", r.Name, err)
+		}
+		return nil
+	})
+}
+
+// checkProcedures checks the bodies of the schema's procedures with check,
+// if not nil, once everything they may refer to is in cat.
+func checkProcedures(cat *bigq.Catalog, routines []schema.Routine, check ProcedureChecker) error {
+	if check == nil {
+		return nil
+	}
+	var errs []error
+	for _, r := range routines {
+		if !r.IsProcedure() {
+			continue
+		}
+		for _, e := range check(cat, routineDDL(r)) {
+			errs = append(errs, fmt.Errorf("procedure %s: %s", r.Name, e.Message))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// routineDDL returns the CREATE FUNCTION, CREATE TABLE FUNCTION or CREATE
+// PROCEDURE statement that defines r.
+func routineDDL(r schema.Routine) string {
+	args := make([]string, len(r.Arguments))
+	for i, a := range r.Arguments {
+		args[i] = quoteIdentifier(a.Name) + " " + a.Type
+		if a.Mode != "" {
+			args[i] = a.Mode + " " + args[i]
+		}
+	}
+
+	var b strings.Builder
+	if r.IsProcedure() {
+		fmt.Fprintf(&b, "CREATE PROCEDURE %s(%s)\n", quoteIdentifier(r.Name), strings.Join(args, ", "))
+		if isBlock(r.Body) {
+			b.WriteString(r.Body)
+		} else {
+			fmt.Fprintf(&b, "BEGIN\n%s\nEND", r.Body)
+		}
+		return b.String()
+	}
+	if r.IsTableFunction() {
+		fmt.Fprintf(&b, "CREATE TABLE FUNCTION %s(%s)", quoteIdentifier(r.Name), strings.Join(args, ", "))
+		if len(r.ReturnTable) > 0 {
+			cols := make([]string, len(r.ReturnTable))
+			for i, c := range r.ReturnTable {
+				cols[i] = quoteIdentifier(c.Name) + " " + c.Type
+			}
+			fmt.Fprintf(&b, " RETURNS TABLE<%s>", strings.Join(cols, ", "))
+		}
+		fmt.Fprintf(&b, " AS %s", r.Body)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "CREATE FUNCTION %s(%s)", quoteIdentifier(r.Name), strings.Join(args, ", "))
+	if r.ReturnType != "" {
+		fmt.Fprintf(&b, " RETURNS %s", r.ReturnType)
+	}
+	if r.IsJavaScript() {
+		fmt.Fprintf(&b, " LANGUAGE js AS %s", strconv.Quote(r.Body))
+	} else {
+		// The newline keeps a trailing comment in the body from swallowing
+		// the closing parenthesis.
+		fmt.Fprintf(&b, " AS (%s\n)", r.Body)
+	}
+	return b.String()
+}
+
+// isBlock reports whether a procedure body is a single BEGIN ... END block,
+// rather than the statements that go in one. A body that fails to parse is
+// not, so that the error is reported inside the block it is wrapped in.
+func isBlock(body string) bool {
+	script, err := bigq.ParseScriptAST(body)
+	if err != nil {
+		return false
+	}
+	stmts := script.Statements()
+	return len(stmts) == 1 && stmts[0].Kind == "BeginEndBlock"
+}
+
+// quoteIdentifier quotes a possibly dotted name as a single identifier.
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "\\`") + "`"
+}
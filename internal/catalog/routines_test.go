@@ -0,0 +1,212 @@
+package catalog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pacer/go-bigq/bigq"
+	"github.com/pacer/go-bigq/internal/lint"
+	"github.com/pacer/go-bigq/internal/schema"
+)
+
+func TestBuildFromSchemaRoutines(t *testing.T) {
+	s := &schema.Schema{
+		Tables: []schema.Table{
+			{Name: "sales.orders", Columns: []schema.Column{{Name: "total", Type: "NUMERIC"}}},
+			{Name: "sales.taxed", Query: "SELECT sales.with_tax(total) AS total FROM sales.orders"},
+		},
+		Routines: []schema.Routine{
+			// Calls a routine defined after it.
+			{
+				Name:      "sales.with_tax",
+				Arguments: []schema.Argument{{Name: "amount", Type: "NUMERIC"}},
+				Body:      "amount * sales.tax_rate()",
+			},
+			{Name: "sales.tax_rate", Body: "NUMERIC '1.2'"},
+			{
+				Name:       "sales.slug",
+				Language:   "JAVASCRIPT",
+				Arguments:  []schema.Argument{{Name: "s", Type: "STRING"}},
+				ReturnType: "STRING",
+				Body:       `return s.toLowerCase().replace(/"/g, "");`,
+			},
+			{
+				Name:      "sales.first",
+				Arguments: []schema.Argument{{Name: "arr", Type: "ANY TYPE"}},
+				Body:      "arr[SAFE_OFFSET(0)]",
+			},
+		},
+	}
+
+	cat, err := BuildFromSchema(s)
+	if err != nil {
+		t.Fatalf("BuildFromSchema: %v", err)
+	}
+	defer cat.Close()
+
+	sql := "SELECT sales.slug('A'), sales.first([1, 2]), total FROM sales.taxed"
+	if _, err := bigq.AnalyzeStatement(sql, cat); err != nil {
+		t.Errorf("AnalyzeStatement(%q): %v", sql, err)
+	}
+}
+
+func TestBuildFromSchemaRoutinesReadingViews(t *testing.T) {
+	s := &schema.Schema{
+		Tables: []schema.Table{
+			{Name: "sales.orders", Columns: []schema.Column{{Name: "total", Type: "NUMERIC"}}},
+			{Name: "sales.big_orders", Query: "SELECT total FROM sales.orders WHERE total > 100"},
+		},
+		Routines: []schema.Routine{
+			{
+				Name: "sales.big_totals",
+				Type: "TABLE_VALUED_FUNCTION",
+				Body: "SELECT total FROM sales.big_orders",
+			},
+			{Name: "sales.big_count", Body: "(SELECT COUNT(*) FROM sales.big_orders)"},
+		},
+	}
+
+	cat, err := BuildFromSchema(s)
+	if err != nil {
+		t.Fatalf("BuildFromSchema: %v", err)
+	}
+	defer cat.Close()
+
+	sql := "SELECT total, sales.big_count() FROM sales.big_totals()"
+	if _, err := bigq.AnalyzeStatement(sql, cat); err != nil {
+		t.Errorf("AnalyzeStatement(%q): %v", sql, err)
+	}
+}
+
+func TestBuildFromSchemaBrokenRoutine(t *testing.T) {
+	s := &schema.Schema{Routines: []schema.Routine{
+		{Name: "f", Arguments: []schema.Argument{{Name: "x", Type: "INT64"}}, Body: "x || 'a'"},
+	}}
+	_, err := BuildFromSchema(s)
+	if err == nil || !strings.Contains(err.Error(), "routine f:") {
+		t.Errorf("BuildFromSchema error = %v, want one naming routine f", err)
+	}
+}
+
+func TestBuildFromSchemaTableFunctions(t *testing.T) {
+	s := &schema.Schema{
+		Tables: []schema.Table{
+			{Name: "sales.orders", Columns: []schema.Column{
+				{Name: "id", Type: "INT64"},
+				{Name: "day", Type: "DATE"},
+				{Name: "total", Type: "NUMERIC"},
+			}},
+		},
+		Routines: []schema.Routine{
+			{
+				Name:      "sales.orders_since",
+				Type:      "TABLE_VALUED_FUNCTION",
+				Arguments: []schema.Argument{{Name: "d", Type: "DATE"}},
+				Body:      "SELECT id, total FROM sales.orders WHERE day >= d",
+			},
+			{
+				Name:        "sales.order_ids",
+				Type:        "TABLE_VALUED_FUNCTION",
+				ReturnTable: []schema.Column{{Name: "id", Type: "INT64"}},
+				Body:        "SELECT id FROM sales.orders",
+			},
+		},
+	}
+
+	cat, err := BuildFromSchema(s)
+	if err != nil {
+		t.Fatalf("BuildFromSchema: %v", err)
+	}
+	defer cat.Close()
+
+	for _, tt := range []struct {
+		sql     string
+		wantErr string
+	}{
+		{sql: "SELECT id, total FROM sales.orders_since(DATE '2024-01-01')"},
+		{sql: "SELECT o.id FROM sales.order_ids() AS o"},
+		{sql: "SELECT day FROM sales.orders_since(DATE '2024-01-01')", wantErr: "Unrecognized name: day"},
+		{sql: "SELECT id FROM sales.orders_since('yesterday')", wantErr: "orders_since"},
+		{sql: "SELECT total FROM sales.order_ids()", wantErr: "Unrecognized name: total"},
+	} {
+		_, err := bigq.AnalyzeStatement(tt.sql, cat)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("AnalyzeStatement(%q): %v", tt.sql, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("AnalyzeStatement(%q) error = %v, want %q", tt.sql, err, tt.wantErr)
+		}
+	}
+}
+
+func TestBuildFromSchemaProcedures(t *testing.T) {
+	orders := schema.Table{Name: "sales.orders", Columns: []schema.Column{{Name: "total", Type: "NUMERIC"}}}
+	s := &schema.Schema{
+		Tables: []schema.Table{orders},
+		Routines: []schema.Routine{
+			{
+				Name:      "sales.order_total",
+				Type:      "PROCEDURE",
+				Arguments: []schema.Argument{{Name: "total", Type: "NUMERIC", Mode: "OUT"}},
+				Body:      "SET total = (SELECT SUM(total) FROM sales.orders);",
+			},
+			{
+				Name: "sales.noop",
+				Type: "PROCEDURE",
+				Body: "BEGIN\n  SELECT 1;\nEND",
+			},
+			{
+				Name: "sales.in_transaction",
+				Type: "PROCEDURE",
+				Body: "BEGIN TRANSACTION;\nSELECT 1;\nCOMMIT TRANSACTION;",
+			},
+		},
+	}
+
+	cat, err := bigq.NewCatalog("root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cat.Close()
+	if err := AddSchema(cat, s, checkProcedure); err != nil {
+		t.Fatalf("AddSchema: %v", err)
+	}
+
+	if p, ok := cat.Procedure("sales.order_total"); !ok || len(p.Arguments) != 1 || p.Arguments[0].Mode != "OUT" {
+		t.Errorf("Procedure(sales.order_total) = %+v, %v", p, ok)
+	}
+	for _, call := range []string{"CALL sales.noop()", "CALL sales.in_transaction()"} {
+		if _, err := bigq.AnalyzeStatement(call, cat); err != nil {
+			t.Errorf("AnalyzeStatement(%s): %v", call, err)
+		}
+	}
+
+	broken := &schema.Schema{
+		Tables:   []schema.Table{orders},
+		Routines: []schema.Routine{{Name: "p", Type: "PROCEDURE", Body: "SELECT missing FROM sales.orders;"}},
+	}
+	if _, err := BuildFromSchema(broken); err != nil {
+		t.Errorf("BuildFromSchema checked a procedure body: %v", err)
+	}
+	cat2, err := bigq.NewCatalog("root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cat2.Close()
+	err = AddSchema(cat2, broken, checkProcedure)
+	if err == nil || !strings.Contains(err.Error(), "procedure p:") {
+		t.Errorf("AddSchema error = %v, want one naming procedure p", err)
+	}
+}
+
+// checkProcedure is a ProcedureChecker backed by the linter, as the command
+// supplies.
+func checkProcedure(cat *bigq.Catalog, sql string) []ProcedureError {
+	var errs []ProcedureError
+	for _, r := range lint.New(cat).LintSQL(sql) {
+		if r.Level == lint.LevelError {
+			errs = append(errs, ProcedureError{Offset: r.Offset, Message: r.Message})
+		}
+	}
+	return errs
+}
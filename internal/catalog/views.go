@@ -0,0 +1,117 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pacer/go-bigq/bigq"
+	"github.com/pacer/go-bigq/internal/schema"
+)
+
+// addViews analyzes view queries and adds each view to cat with the columns
+// its query produces. A view that selects from another view is analyzed
+// after it.
+func addViews(cat *bigq.Catalog, views []schema.Table) error {
+	deps := make([][]int, len(views))
+	for i, v := range views {
+		for _, ref := range tableRefs(v.Query) {
+			for j, w := range views {
+				if j != i && refersTo(ref, w.Name) {
+					deps[i] = append(deps[i], j)
+				}
+			}
+		}
+	}
+
+	broken := make(map[int]bool)
+	var errs []error
+	for _, i := range dependencyOrder(deps) {
+		v := views[i]
+		err := brokenDependency(views, deps[i], broken)
+		if err == nil {
+			err = addView(cat, v)
+		}
+		if err != nil {
+			broken[i] = true
+			errs = append(errs, fmt.Errorf("view %s: %w", v.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func addView(cat *bigq.Catalog, v schema.Table) error {
+	out, err := bigq.AnalyzeStatement(v.Query, cat)
+	if err != nil {
+		return err
+	}
+	if out.StatementKind != "QueryStmt" {
+		return fmt.Errorf("query is a %s statement, not a query", out.StatementKind)
+	}
+	return cat.AddTable(v.Name, out.OutputColumns)
+}
+
+// brokenDependency reports the first of deps that failed to analyze.
+func brokenDependency(views []schema.Table, deps []int, broken map[int]bool) error {
+	for _, j := range deps {
+		if broken[j] {
+			return fmt.Errorf("depends on broken view %s", views[j].Name)
+		}
+	}
+	return nil
+}
+
+// tableRefs returns the table paths a query reads from, joined with dots.
+// A query that doesn't parse has none; analysis reports the syntax error.
+func tableRefs(query string) []string {
+	script, err := bigq.ParseScriptAST(query)
+	if err != nil {
+		return nil
+	}
+	var refs []string
+	for _, n := range script.Root.Find("TablePathExpression") {
+		for _, c := range n.Children {
+			if path := c.Path(); len(path) > 0 {
+				refs = append(refs, strings.Join(path, "."))
+			}
+		}
+	}
+	return refs
+}
+
+// refersTo reports whether a table reference can name the table with the
+// given full name, either exactly or with its project or dataset left to
+// the defaults.
+func refersTo(ref, name string) bool {
+	ref, name = strings.ToLower(ref), strings.ToLower(name)
+	return ref == name || strings.HasSuffix(name, "."+ref)
+}
+
+// dependencyOrder returns the indexes of a dependency graph with every node
+// after the nodes it depends on, keeping the input order otherwise. Cycles
+// are broken arbitrarily; analysis then reports the views involved.
+func dependencyOrder(deps [][]int) []int {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(deps))
+	var order []int
+	var visit func(int)
+	visit = func(i int) {
+		if state[i] != unvisited {
+			return
+		}
+		state[i] = visiting
+		for _, j := range deps[i] {
+			visit(j)
+		}
+		state[i] = done
+		order = append(order, i)
+	}
+	for i := range deps {
+		visit(i)
+	}
+	return order
+}
@@ -0,0 +1,60 @@
+package catalog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pacer/go-bigq/bigq"
+	"github.com/pacer/go-bigq/internal/schema"
+)
+
+func TestBuildFromSchemaViews(t *testing.T) {
+	s := &schema.Schema{Tables: []schema.Table{
+		// Declared before the view it selects from.
+		{Name: "sales.top_customers", Query: "SELECT customer, SUM(total) AS spent FROM sales.big_orders GROUP BY customer"},
+		{Name: "sales.big_orders", Query: "SELECT id, customer, total FROM `sales.orders` WHERE total > 100"},
+		{Name: "sales.orders", Columns: []schema.Column{
+			{Name: "id", Type: "INT64"},
+			{Name: "customer", Type: "STRING"},
+			{Name: "total", Type: "NUMERIC"},
+		}},
+	}}
+
+	cat, err := BuildFromSchema(s)
+	if err != nil {
+		t.Fatalf("BuildFromSchema: %v", err)
+	}
+	defer cat.Close()
+
+	columns, ok := cat.Table("sales.top_customers")
+	if !ok {
+		t.Fatal("view sales.top_customers not in catalog")
+	}
+	want := []bigq.ColumnDef{{Name: "customer", TypeName: "STRING"}, {Name: "spent", TypeName: "NUMERIC"}}
+	if len(columns) != len(want) || columns[0] != want[0] || columns[1] != want[1] {
+		t.Errorf("view columns = %v, want %v", columns, want)
+	}
+}
+
+func TestBuildFromSchemaBrokenViews(t *testing.T) {
+	s := &schema.Schema{Tables: []schema.Table{
+		{Name: "orders", Columns: []schema.Column{{Name: "id", Type: "INT64"}}},
+		{Name: "renamed", Query: "SELECT order_id FROM orders"},
+		{Name: "downstream", Query: "SELECT * FROM renamed"},
+		{Name: "fine", Query: "SELECT id FROM orders"},
+	}}
+
+	_, err := BuildFromSchema(s)
+	if err == nil {
+		t.Fatal("BuildFromSchema succeeded with a broken view")
+	}
+	msg := err.Error()
+	for _, want := range []string{"view renamed:", "view downstream: depends on broken view renamed"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error %q does not mention %q", msg, want)
+		}
+	}
+	if strings.Contains(msg, "view fine") {
+		t.Errorf("error %q mentions a valid view", msg)
+	}
+}
@@ -0,0 +1,155 @@
+package lint
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pacer/go-bigq/bigq"
+)
+
+// insert checks that an INSERT statement does not leave a REQUIRED column
+// of its target NULL: a column list must name every REQUIRED column, and
+// no row may put a literal NULL in one. The analyzer accepts both, since
+// GoogleSQL tables have no NOT NULL constraint.
+func (s *scriptLinter) insert(n *bigq.Node) {
+	table, columns, ok := s.dmlTarget(n)
+	if !ok || !hasRequired(columns) {
+		return
+	}
+
+	var rows [][]*bigq.Node
+	if list := childOfKind(n, "InsertValuesRowList"); list != nil {
+		for _, row := range list.Children {
+			rows = append(rows, row.Children)
+		}
+	} else if query := childOfKind(n, "Query"); query != nil {
+		if values := selectValues(query); values != nil {
+			rows = append(rows, values)
+		}
+	}
+	s.insertRows(table, columns, childOfKind(n, "ColumnList"), rows)
+}
+
+// merge checks the INSERT clauses of a MERGE statement as insert checks an
+// INSERT statement, and its UPDATE clauses as update does.
+func (s *scriptLinter) merge(n *bigq.Node) {
+	table, columns, ok := s.dmlTarget(n)
+	if !ok || !hasRequired(columns) {
+		return
+	}
+	for _, action := range n.Find("MergeAction") {
+		// INSERT ROW has an empty row: the source supplies every column.
+		row := childOfKind(action, "InsertValuesRow")
+		if row == nil || len(row.Children) == 0 {
+			continue
+		}
+		s.insertRows(table, columns, childOfKind(action, "ColumnList"), [][]*bigq.Node{row.Children})
+	}
+	s.update(n)
+}
+
+// insertRows checks the column list, if any, and the rows of values that an
+// INSERT puts into table, whose columns are columns.
+func (s *scriptLinter) insertRows(table string, columns []bigq.ColumnDef, list *bigq.Node, rows [][]*bigq.Node) {
+	// names are the target columns in the order rows supply them.
+	var names []string
+	if list != nil {
+		for _, id := range list.Children {
+			names = append(names, id.Name)
+		}
+		for _, c := range columns {
+			if c.Required() && !slices.ContainsFunc(names, func(name string) bool { return strings.EqualFold(name, c.Name) }) {
+				s.errorAt(list, CodeRequiredColumn, fmt.Sprintf("INSERT into %s omits REQUIRED column %s", table, c.Name))
+			}
+		}
+	} else {
+		for _, c := range columns {
+			if !c.Pseudo {
+				names = append(names, c.Name)
+			}
+		}
+	}
+
+	for _, values := range rows {
+		for i, v := range values {
+			if i < len(names) && v.Kind == "NullLiteral" && isRequired(columns, names[i]) {
+				s.errorAt(v, CodeRequiredColumn, fmt.Sprintf("cannot insert NULL into REQUIRED column %s of %s", names[i], table))
+			}
+		}
+	}
+}
+
+// update checks that an UPDATE statement, or the UPDATE clauses of a MERGE,
+// do not set a REQUIRED column of the target to a literal NULL.
+func (s *scriptLinter) update(n *bigq.Node) {
+	table, columns, ok := s.dmlTarget(n)
+	if !ok || !hasRequired(columns) {
+		return
+	}
+	for _, set := range n.Find("UpdateSetValue") {
+		if len(set.Children) != 2 || set.Children[1].Kind != "NullLiteral" {
+			continue
+		}
+		if path := set.Children[0].Path(); len(path) == 1 && isRequired(columns, path[0]) {
+			s.errorAt(set.Children[1], CodeRequiredColumn, fmt.Sprintf("cannot set REQUIRED column %s of %s to NULL", path[0], table))
+		}
+	}
+}
+
+// dmlTarget returns the name and columns of the table a DML statement
+// writes, as the script currently sees it.
+func (s *scriptLinter) dmlTarget(n *bigq.Node) (string, []bigq.ColumnDef, bool) {
+	target := childOfKind(n, "PathExpression")
+	if target == nil {
+		return "", nil, false
+	}
+	table := strings.Join(target.Path(), ".")
+	columns, ok := s.overlay.Table(table)
+	return table, columns, ok
+}
+
+// selectValues returns the expressions of a query that is a single SELECT
+// of plain expressions, in order, or nil for any other query, whose
+// columns cannot be matched to the target's by position.
+func selectValues(query *bigq.Node) []*bigq.Node {
+	sel := childOfKind(query, "Select")
+	if sel == nil || len(query.Children) != 1 {
+		return nil
+	}
+	list := childOfKind(sel, "SelectList")
+	if list == nil {
+		return nil
+	}
+	values := make([]*bigq.Node, 0, len(list.Children))
+	for _, col := range list.Children {
+		if len(col.Children) == 0 || !col.Children[0].IsExpression() || strings.Contains(col.Children[0].Kind, "Star") {
+			return nil
+		}
+		values = append(values, col.Children[0])
+	}
+	return values
+}
+
+// childOfKind returns the first child of n with the given kind, or nil.
+func childOfKind(n *bigq.Node, kind string) *bigq.Node {
+	for _, c := range n.Children {
+		if c.Kind == kind {
+			return c
+		}
+	}
+	return nil
+}
+
+func hasRequired(columns []bigq.ColumnDef) bool {
+	return slices.ContainsFunc(columns, bigq.ColumnDef.Required)
+}
+
+func isRequired(columns []bigq.ColumnDef, name string) bool {
+	for _, c := range columns {
+		if strings.EqualFold(c.Name, name) {
+			return c.Required()
+		}
+	}
+	return false
+}
@@ -2,30 +2,87 @@
 package lint
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/pacer/go-bigq/bigq"
 )
 
-// Result represents a single lint finding.
+// Result represents a single lint finding. It covers the source range from
+// Line:Column up to, but not including, EndLine:EndColumn; the offsets
+// are the same positions as byte offsets in the file.
 type Result struct {
-	File    string `json:"file"`
-	Line    int    `json:"line"`   // 1-based
-	Column  int    `json:"column"` // 1-based
-	Level   string `json:"level"`  // "error" or "warning"
-	Message string `json:"message"`
+	File      string `json:"file"`
+	Line      int    `json:"line"`      // 1-based
+	Column    int    `json:"column"`    // 1-based, counting characters
+	EndLine   int    `json:"endLine"`   // 1-based
+	EndColumn int    `json:"endColumn"` // 1-based, counting characters
+	Offset    int    `json:"offset"`    // 0-based
+	EndOffset int    `json:"endOffset"` // 0-based
+	Level     string `json:"level"`     // LevelError, LevelWarning or LevelNotice
+	Code      string `json:"code"`      // a bigq.ErrorKind or one of the Code constants
+	Message   string `json:"message"`
+
+	// Suggestions are the names that may have been meant by an unknown
+	// table, column, function or keyword, most likely first.
+	Suggestions []string `json:"suggestions,omitempty"`
 }
 
+// newResult returns a result covering sql[start:end].
+func newResult(sql string, start, end int, level, code, msg string) Result {
+	start = min(max(start, 0), len(sql))
+	end = min(max(end, start), len(sql))
+	r := Result{Offset: start, EndOffset: end, Level: level, Code: code, Message: msg}
+	r.Line, r.Column = bigq.Position(sql, start)
+	r.EndLine, r.EndColumn = bigq.Position(sql, end)
+	return r
+}
+
+// Result levels.
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelNotice  = "notice" // informational, such as an inferred parameter type
+)
+
+// Result codes of the checks the linter makes itself. Errors reported by
+// the parser or analyzer have the code of their bigq.ErrorKind.
+const (
+	CodeUnusedVariable     = "unused-variable"
+	CodeUndeclaredVariable = "undeclared-variable"
+	CodeDroppedTable       = "dropped-table"
+	CodeProcedureArgument  = "procedure-argument"
+	CodeRequiredColumn     = "required-column"
+	CodeErrorVariable      = "error-variable"
+	CodeInferredParameter  = "inferred-parameter"
+)
+
 func (r Result) String() string {
 	if r.File != "" && r.Line > 0 {
-		return fmt.Sprintf("%s:%d:%d: %s: %s", r.File, r.Line, r.Column, r.Level, r.Message)
+		return fmt.Sprintf("%s:%d:%d: %s: %s", r.File, r.Line, r.Column, r.Level, r.Detail())
 	}
 	if r.File != "" {
-		return fmt.Sprintf("%s: %s: %s", r.File, r.Level, r.Message)
+		return fmt.Sprintf("%s: %s: %s", r.File, r.Level, r.Detail())
 	}
-	return fmt.Sprintf("%s: %s", r.Level, r.Message)
+	return fmt.Sprintf("%s: %s", r.Level, r.Detail())
+}
+
+// Detail returns the message followed by the suggestions, unless the
+// message already makes one.
+func (r Result) Detail() string {
+	if len(r.Suggestions) == 0 || strings.Contains(r.Message, "Did you mean") {
+		return r.Message
+	}
+	last := len(r.Suggestions) - 1
+	if last == 0 {
+		return fmt.Sprintf("%s (did you mean %s?)", r.Message, r.Suggestions[0])
+	}
+	return fmt.Sprintf("%s (did you mean %s or %s?)", r.Message, strings.Join(r.Suggestions[:last], ", "), r.Suggestions[last])
 }
 
 // Linter validates SQL statements against a catalog.
@@ -39,51 +96,71 @@ func New(catalog *bigq.Catalog) *Linter {
 }
 
 // LintSQL checks a SQL string (potentially multi-statement) for errors.
-// It uses ZetaSQL's ParseScript to validate the full script including
-// scripting constructs (DECLARE, SET, IF, ASSERT, etc.). When a catalog
-// is provided, individual non-scripting statements are additionally
-// analyzed for schema conformance.
+// It uses ZetaSQL's script parser to validate the full script including
+// scripting constructs (DECLARE, SET, IF, ASSERT, etc.), reporting every
+// statement with a syntax error rather than only the first. When a
+// catalog is provided, individual non-scripting statements are
+// additionally analyzed for schema conformance.
 func (l *Linter) LintSQL(sql string) []Result {
-	// ParseScript validates the entire script including scripting syntax.
-	if err := bigq.ParseScript(sql); err != nil {
-		return []Result{{
-			Line:    1,
-			Column:  1,
-			Level:   "error",
-			Message: err.Error(),
-		}}
+	stmts := bigq.ParseScriptStatements(sql)
+
+	var results []Result
+	for _, stmt := range stmts {
+		if stmt.Err == nil {
+			continue
+		}
+		// A located syntax error covers the token it points at, otherwise
+		// the whole statement.
+		// A misspelled keyword there gets suggestions. So does one just
+		// before it: after an expression, a misspelled keyword parses as
+		// an alias, as FORM does in SELECT id FORM t, and the error is at
+		// the word after it.
+		start, end := stmt.Start, stmt.End
+		var suggestions []string
+		if stmt.ErrOffset >= 0 {
+			start, end = stmt.ErrOffset, tokenEnd(sql, stmt.ErrOffset)
+			suggestions = keywordSuggestions(sql[start:end])
+			if suggestions == nil {
+				suggestions = keywordSuggestions(wordBefore(sql, start))
+			}
+		}
+		r := newResult(sql, start, end, LevelError, errorCode(stmt.Err), stmt.Err.Error())
+		r.Suggestions = suggestions
+		results = append(results, r)
 	}
 
 	// Without a catalog, syntax validation is all we can do.
 	if l.catalog == nil {
-		return nil
+		return results
 	}
 
 	// With a catalog, analyze individual statements for schema conformance.
-	// AnalyzeStatement doesn't support scripting constructs, so we split
-	// and skip those.
-	var results []Result
-	for _, stmt := range splitStatements(sql) {
-		trimmed := strings.TrimSpace(stmt.text)
-		if trimmed == "" || trimmed == ";" {
-			continue
-		}
-		if isScriptingStatement(trimmed) {
-			continue
-		}
-
-		if err := bigq.AnalyzeStatement(trimmed, l.catalog); err != nil {
-			results = append(results, Result{
-				Line:    stmt.startLine,
-				Column:  1,
-				Level:   "error",
-				Message: err.Error(),
-			})
+	// Statement boundaries come from the parser, so the analyzed text is
+	// exactly what the parser saw. AnalyzeStatement doesn't support
+	// scripting constructs, so the script is walked in order: statements
+	// nested inside blocks are analyzed one by one, and DECLAREd variables
+	// are made visible to the statements that follow.
+	script := newScriptLinter(sql, l.catalog)
+	for _, stmt := range stmts {
+		if stmt.Node != nil {
+			script.statement(stmt.Node)
 		}
 	}
+	results = append(results, script.finish()...)
+	sortResults(results)
 	return results
 }
 
+// sortResults orders results by position in the file.
+func sortResults(results []Result) {
+	slices.SortStableFunc(results, func(a, b Result) int {
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Column - b.Column
+	})
+}
+
 // LintFile reads and lints a SQL file.
 func (l *Linter) LintFile(path string) ([]Result, error) {
 	data, err := os.ReadFile(path)
@@ -98,139 +175,48 @@ func (l *Linter) LintFile(path string) ([]Result, error) {
 	return results, nil
 }
 
-// scriptingKeywords are BigQuery scripting keywords that ZetaSQL's
-// statement-level analyzer doesn't support. These are skipped during
-// schema analysis but are validated by ParseScript.
-var scriptingKeywords = []string{
-	"DECLARE", "SET", "ASSERT",
-	"IF", "ELSEIF", "ELSE", "END",
+// errorCode returns the code of a parser or analyzer error.
+func errorCode(err error) string {
+	var e *bigq.Error
+	if errors.As(err, &e) {
+		return string(e.Kind)
+	}
+	return string(bigq.KindAnalysis)
 }
 
-// isScriptingStatement reports whether trimmed starts with a BigQuery
-// scripting keyword that ZetaSQL's analyzer cannot handle.
-func isScriptingStatement(trimmed string) bool {
-	upper := strings.ToUpper(trimmed)
-	for _, kw := range scriptingKeywords {
-		if !strings.HasPrefix(upper, kw) {
-			continue
-		}
-		// Keyword must be the entire statement or followed by whitespace.
-		if len(upper) == len(kw) {
-			return true
-		}
-		switch upper[len(kw)] {
-		case ' ', '\t', '\n', '\r':
-			return true
+// wordBefore returns the run of letters, digits and underscores that ends
+// at the last non-space character before offset in sql, empty if that
+// character is none of them.
+func wordBefore(sql string, offset int) string {
+	end := len(strings.TrimRightFunc(sql[:offset], unicode.IsSpace))
+	start := end
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(sql[:start])
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
 		}
+		start -= size
 	}
-	return false
-}
-
-type stmtSpan struct {
-	text      string
-	startLine int
+	return sql[start:end]
 }
 
-// splitStatements splits SQL on semicolons, tracking line numbers.
-// Used for per-statement schema analysis when a catalog is provided.
-func splitStatements(sql string) []stmtSpan {
-	var spans []stmtSpan
-	line := 1
-	start := 0
-	startLine := 1
-	inSingleQuote := false
-	inDoubleQuote := false
-	inBacktick := false
-	inLineComment := false
-	inBlockComment := false
-
-	for i := 0; i < len(sql); i++ {
-		c := sql[i]
-
-		if c == '\n' {
-			line++
-			if inLineComment {
-				inLineComment = false
-			}
-			continue
-		}
-
-		if inLineComment {
-			continue
-		}
-
-		if inBlockComment {
-			if c == '*' && i+1 < len(sql) && sql[i+1] == '/' {
-				inBlockComment = false
-				i++
-			}
-			continue
-		}
-
-		if inSingleQuote {
-			switch c {
-			case '\'':
-				inSingleQuote = false
-			case '\\':
-				i++ // skip escaped char
-			}
-			continue
-		}
-
-		if inDoubleQuote {
-			switch c {
-			case '"':
-				inDoubleQuote = false
-			case '\\':
-				i++
-			}
-			continue
-		}
-
-		if inBacktick {
-			if c == '`' {
-				inBacktick = false
-			}
-			continue
-		}
-
-		switch c {
-		case '\'':
-			inSingleQuote = true
-		case '"':
-			inDoubleQuote = true
-		case '`':
-			inBacktick = true
-		case '-':
-			if i+1 < len(sql) && sql[i+1] == '-' {
-				inLineComment = true
-				i++
-			}
-		case '/':
-			if i+1 < len(sql) && sql[i+1] == '*' {
-				inBlockComment = true
-				i++
-			}
-		case ';':
-			spans = append(spans, stmtSpan{
-				text:      sql[start:i],
-				startLine: startLine,
-			})
-			start = i + 1
-			startLine = line
-		}
+// tokenEnd returns the end of the token starting at offset in sql: a run
+// of letters, digits and underscores, or else a single character.
+func tokenEnd(sql string, offset int) int {
+	if offset >= len(sql) {
+		return len(sql)
 	}
-
-	// Remaining text after last semicolon
-	if start < len(sql) {
-		remaining := strings.TrimSpace(sql[start:])
-		if remaining != "" {
-			spans = append(spans, stmtSpan{
-				text:      sql[start:],
-				startLine: startLine,
-			})
+	end := offset
+	for end < len(sql) {
+		r, size := utf8.DecodeRuneInString(sql[end:])
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
 		}
+		end += size
 	}
-
-	return spans
+	if end == offset {
+		_, size := utf8.DecodeRuneInString(sql[offset:])
+		end += size
+	}
+	return end
 }
@@ -1,36 +1,12 @@
 package lint
 
 import (
+	"slices"
+	"strings"
 	"testing"
-)
-
-func TestSplitStatements(t *testing.T) {
-	tests := []struct {
-		name  string
-		sql   string
-		count int
-	}{
-		{"single", "SELECT 1", 1},
-		{"two", "SELECT 1; SELECT 2", 2},
-		{"trailing semi", "SELECT 1;", 1},
-		{"empty between", "SELECT 1;; SELECT 2", 3},
-		{"with comments", "-- comment\nSELECT 1;\n/* block */\nSELECT 2", 2},
-		{"string with semi", "SELECT 'a;b'", 1},
-		{"backtick with semi", "SELECT `a;b`", 1},
-	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			spans := splitStatements(tt.sql)
-			if len(spans) != tt.count {
-				t.Errorf("splitStatements(%q) = %d statements, want %d", tt.sql, len(spans), tt.count)
-				for i, s := range spans {
-					t.Logf("  [%d] line=%d text=%q", i, s.startLine, s.text)
-				}
-			}
-		})
-	}
-}
+	"github.com/pacer/go-bigq/bigq"
+)
 
 func TestLintSQL_ScriptingStatements(t *testing.T) {
 	l := &Linter{} // parse-only via ParseScript, no catalog
@@ -77,6 +53,35 @@ func TestLintSQL_ScriptingStatements(t *testing.T) {
 	}
 }
 
+func TestLintSQL_PlainStatementsLikeScripting(t *testing.T) {
+	// Function calls that start like scripting keywords are plain SQL and
+	// must be analyzed as such.
+	tests := []struct {
+		sql      string
+		typeName string // type of t.x
+	}{
+		{"SELECT IF(x, 1, 2) FROM t", "BOOL"},
+		{"SELECT IFNULL(x, 0) FROM t", "INT64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			cat, err := bigq.NewCatalog("test")
+			if err != nil {
+				t.Fatalf("NewCatalog: %v", err)
+			}
+			defer cat.Close()
+			if err := cat.AddTable("t", []bigq.ColumnDef{{Name: "x", TypeName: tt.typeName}}); err != nil {
+				t.Fatalf("AddTable: %v", err)
+			}
+
+			if results := New(cat).LintSQL(tt.sql); len(results) != 0 {
+				t.Errorf("LintSQL(%q) = %v, want no results", tt.sql, results)
+			}
+		})
+	}
+}
+
 func TestLintSQL_SyntaxErrors(t *testing.T) {
 	l := &Linter{}
 
@@ -98,34 +103,291 @@ func TestLintSQL_SyntaxErrors(t *testing.T) {
 	}
 }
 
-func TestIsScriptingStatement(t *testing.T) {
-	// Should NOT be detected as scripting.
-	falsePositives := []string{
-		"SELECT 1",
-		"INSERT INTO t VALUES (1)",
-		"CREATE TABLE t (x INT64)",
-		"SELECT IF(x, 1, 2) FROM t",
-		"SELECT IFNULL(x, 0) FROM t",
-		"SELECT SETTINGS FROM t",
-	}
-	for _, sql := range falsePositives {
-		if isScriptingStatement(sql) {
-			t.Errorf("isScriptingStatement(%q) = true, want false", sql)
+func TestLintSQL_ReportsEverySyntaxError(t *testing.T) {
+	l := &Linter{}
+
+	sql := "SELECT 1;\nSELECT * FORM t;\nSELECT 2;\nSELECT * FORM u;\n"
+	results := l.LintSQL(sql)
+	if len(results) != 2 {
+		t.Fatalf("LintSQL returned %d errors, want 2", len(results))
+	}
+
+	wantLines := []int{2, 4}
+	for i, r := range results {
+		if r.Line != wantLines[i] {
+			t.Errorf("error %d: line = %d, want %d (%s)", i, r.Line, wantLines[i], r)
+		}
+		if r.Column <= 1 {
+			t.Errorf("error %d: column = %d, want the position of FORM", i, r.Column)
 		}
 	}
 }
 
-func TestSplitStatementsLineTracking(t *testing.T) {
-	sql := "SELECT 1;\n\nSELECT 2;\nSELECT 3"
-	spans := splitStatements(sql)
-	if len(spans) != 3 {
-		t.Fatalf("expected 3 statements, got %d", len(spans))
+func TestLintSQL_SyntaxErrorInBlock(t *testing.T) {
+	l := &Linter{}
+
+	tests := []struct {
+		name string
+		sql  string
+	}{
+		{"if", "IF true THEN\n  SELECT * FORM t;\n  SELECT 1;\nEND IF;\nSELECT * FORM u;\n"},
+		{"nested if", "IF true THEN\n  IF false THEN\n    SELECT * FORM t;\n  END IF;\n  SELECT 1;\nEND IF;\nSELECT * FORM u;\n"},
+		{"begin", "BEGIN\n  SELECT * FORM t;\n  SELECT CASE WHEN true THEN 1 END;\nEND;\nSELECT * FORM u;\n"},
+		{"loop", "LOOP\n  SELECT * FORM t;\n  BREAK;\nEND LOOP;\nSELECT * FORM u;\n"},
+		{"procedure", "CREATE PROCEDURE ds.p(x INT64)\nBEGIN\n  SELECT * FORM t;\n  SELECT x;\nEND;\nSELECT * FORM u;\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := l.LintSQL(tt.sql)
+			if len(results) != 2 {
+				t.Fatalf("LintSQL returned %d errors, want 2: %v", len(results), results)
+			}
+			if !strings.Contains(results[0].Message, "FORM") {
+				t.Errorf("first error = %s, want the one in the block", results[0])
+			}
+			if want := strings.Count(tt.sql, "\n"); results[1].Line != want {
+				t.Errorf("second error at line %d, want %d", results[1].Line, want)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"FROM", "FROM", 0},
+		{"from", "FROM", 0},
+		{"FORM", "FROM", 1}, // transposition
+		{"SELEC", "SELECT", 1},
+		{"nmae", "name", 1},
+		{"ordrs", "orders", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+		{"café", "cafe", 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
 	}
+}
 
-	expected := []int{1, 1, 3}
-	for i, span := range spans {
-		if span.startLine != expected[i] {
-			t.Errorf("statement %d: startLine = %d, want %d", i, span.startLine, expected[i])
+func TestClosest(t *testing.T) {
+	candidates := []string{"FOR", "FROM", "FORMAT", "FULL", "SELECT"}
+	if got, want := closest("FORM", candidates), []string{"FROM", "FOR"}; !slices.Equal(got, want) {
+		t.Errorf("closest(FORM) = %v, want %v", got, want)
+	}
+	if got := closest("id", []string{"ix", "is"}); got != nil {
+		t.Errorf("closest(id) = %v, want none for a name this short", got)
+	}
+	if got := closest("FROM", candidates); slices.Contains(got, "FROM") {
+		t.Errorf("closest(FROM) = %v, suggests the name itself", got)
+	}
+}
+
+func TestWordBefore(t *testing.T) {
+	tests := []struct {
+		sql    string
+		offset int
+		want   string
+	}{
+		{"SELECT id FORM t", 15, "FORM"},
+		{"SELECT id FORM\n  t", 17, "FORM"},
+		{"SELECT (1) t", 11, ""},
+		{"t", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := wordBefore(tt.sql, tt.offset); got != tt.want {
+			t.Errorf("wordBefore(%q, %d) = %q, want %q", tt.sql, tt.offset, got, tt.want)
+		}
+	}
+}
+
+func TestLintSQL_Suggestions(t *testing.T) {
+	cat := newTestCatalog(t)
+	for _, sql := range []string{
+		"CREATE FUNCTION ds.normalize_name(s STRING) AS (LOWER(s))",
+		"CREATE FUNCTION ds.cleanName(s STRING) AS (TRIM(s))",
+	} {
+		if err := cat.AddRoutine(sql); err != nil {
+			t.Fatalf("AddRoutine: %v", err)
 		}
 	}
+	l := New(cat)
+
+	tests := []struct {
+		name string
+		sql  string
+		want string // a suggestion the result must have
+	}{
+		{"table", "SELECT id FROM my_tabel;", "my_table"},
+		{"quoted table", "SELECT id FROM `my_tabel` AS t;", "my_table"},
+		{"column", "SELECT nmae FROM my_table;", "name"},
+		{"variable", "DECLARE row_limit INT64 DEFAULT 10;\nSELECT id FROM my_table WHERE id > row_limt OR id < row_limit;", "row_limit"},
+		{"builtin function", "SELECT CONACT(name) FROM my_table;", "CONCAT"},
+		{"user function", "SELECT ds.normalise_name(name) FROM my_table;", "ds.normalize_name"},
+		{"user function in its defined case", "SELECT DS.CLEANNAM(name) FROM my_table;", "ds.cleanName"},
+		{"keyword", "SELECT * FORM my_table;", "FROM"},
+		{"keyword read as an alias", "SELECT id FORM my_table;", "FROM"},
+		{"lower-case keyword", "selct id from my_table;", "select"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := l.LintSQL(tt.sql)
+			if len(results) != 1 {
+				t.Fatalf("LintSQL(%q) returned %d results, want 1: %v", tt.sql, len(results), results)
+			}
+			if r := results[0]; !slices.Contains(r.Suggestions, tt.want) {
+				t.Errorf("suggestions = %q, want %q among them (%s)", r.Suggestions, tt.want, r)
+			}
+		})
+	}
+
+	// A name nothing resembles gets no suggestion.
+	results := l.LintSQL("SELECT id FROM completely_unrelated;")
+	if len(results) != 1 || results[0].Suggestions != nil {
+		t.Errorf("LintSQL = %v, want one result without suggestions", results)
+	}
+}
+
+func TestResultDetail(t *testing.T) {
+	r := Result{Message: "Table not found: ordrs", Suggestions: []string{"orders"}}
+	if got, want := r.Detail(), "Table not found: ordrs (did you mean orders?)"; got != want {
+		t.Errorf("Detail() = %q, want %q", got, want)
+	}
+	r.Suggestions = []string{"FROM", "FOR"}
+	if got, want := r.Detail(), "Table not found: ordrs (did you mean FROM or FOR?)"; got != want {
+		t.Errorf("Detail() = %q, want %q", got, want)
+	}
+	r = Result{Message: "Unrecognized name: nmae; Did you mean name?", Suggestions: []string{"name"}}
+	if got := r.Detail(); got != r.Message {
+		t.Errorf("Detail() = %q, repeats the message's own suggestion", got)
+	}
+}
+
+func TestLintSQL_ResultRanges(t *testing.T) {
+	l := New(newTestCatalog(t))
+
+	tests := []struct {
+		name string
+		sql  string
+		want string // the text the result covers
+		code string
+	}{
+		{"unknown column", "SELECT id,\n  nonexistent FROM my_table;", "nonexistent", "unknown-column"},
+		{"unknown table", "SELECT 1;\nSELECT id FROM missing_table;", "missing_table", "unknown-table"},
+		{"nested statement", "IF true THEN\n  SELECT id FROM my_table WHERE name = 1;\nEND IF;", "name = 1", "signature-mismatch"},
+		{"assignment", "DECLARE n INT64;\nSET n = 'abc';\nSELECT n;", "'abc'", "type-mismatch"},
+		{"syntax error", "SELECT * FORM my_table;", "FORM", "syntax"},
+		{"unused variable", "DECLARE unused_var INT64;", "unused_var", CodeUnusedVariable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := l.LintSQL(tt.sql)
+			if len(results) != 1 {
+				t.Fatalf("LintSQL(%q) returned %d results, want 1: %v", tt.sql, len(results), results)
+			}
+			r := results[0]
+			if got := tt.sql[r.Offset:r.EndOffset]; got != tt.want {
+				t.Errorf("result covers %q, want %q (%s)", got, tt.want, r)
+			}
+			start := strings.Index(tt.sql, tt.want)
+			line, col := bigq.Position(tt.sql, start)
+			endLine, endCol := bigq.Position(tt.sql, start+len(tt.want))
+			if r.Line != line || r.Column != col || r.EndLine != endLine || r.EndColumn != endCol {
+				t.Errorf("range = %d:%d-%d:%d, want %d:%d-%d:%d",
+					r.Line, r.Column, r.EndLine, r.EndColumn, line, col, endLine, endCol)
+			}
+			if r.Code != tt.code {
+				t.Errorf("code = %q, want %q (%s)", r.Code, tt.code, r)
+			}
+			if strings.Contains(r.Message, "1:") {
+				t.Errorf("message %q keeps a statement-relative position", r.Message)
+			}
+		})
+	}
+}
+
+func newTestCatalog(t *testing.T) *bigq.Catalog {
+	t.Helper()
+	cat, err := bigq.NewCatalog("test")
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	t.Cleanup(cat.Close)
+
+	err = cat.AddTable("my_table", []bigq.ColumnDef{
+		{Name: "id", TypeName: "INT64"},
+		{Name: "name", TypeName: "STRING"},
+	})
+	if err != nil {
+		t.Fatalf("AddTable: %v", err)
+	}
+	return cat
+}
+
+func TestLintSQL_StatementBoundaries(t *testing.T) {
+	l := New(newTestCatalog(t))
+
+	// Semicolons inside triple-quoted and raw strings must not split
+	// statements, and the bad column must be reported on its own line.
+	sql := "SELECT \"\"\"a;\nb;\"\"\" AS s FROM my_table;\n" +
+		"SELECT r'x;y' AS r, id FROM my_table;\n" +
+		"\n" +
+		"SELECT nonexistent FROM my_table;\n"
+	results := l.LintSQL(sql)
+	if len(results) != 1 {
+		t.Fatalf("LintSQL returned %d results, want 1: %v", len(results), results)
+	}
+	if results[0].Line != 5 {
+		t.Errorf("line = %d, want 5 (%s)", results[0].Line, results[0])
+	}
+}
+
+func TestLintSQL_AnalyzesAroundSyntaxErrors(t *testing.T) {
+	l := New(newTestCatalog(t))
+
+	sql := "SELECT * FORM my_table;\nSELECT nonexistent FROM my_table;\n"
+	results := l.LintSQL(sql)
+	if len(results) != 2 {
+		t.Fatalf("LintSQL returned %d results, want 2: %v", len(results), results)
+	}
+	if results[0].Line != 1 || results[1].Line != 2 {
+		t.Errorf("lines = %d, %d, want 1, 2", results[0].Line, results[1].Line)
+	}
+}
+
+func TestLintSQL_NestedStatements(t *testing.T) {
+	l := New(newTestCatalog(t))
+
+	tests := []struct {
+		name string
+		sql  string
+	}{
+		{"if", "IF true THEN\n  SELECT nonexistent FROM my_table;\nEND IF;"},
+		{"elseif", "IF false THEN\n  SELECT 1;\nELSEIF true THEN\n  SELECT nonexistent FROM my_table;\nEND IF;"},
+		{"else", "IF false THEN\n  SELECT 1;\nELSE\n  SELECT nonexistent FROM my_table;\nEND IF;"},
+		{"begin end", "BEGIN\n  SELECT nonexistent FROM my_table;\nEND;"},
+		{"exception handler", "BEGIN\n  SELECT 1;\nEXCEPTION WHEN ERROR THEN\n  SELECT nonexistent FROM my_table;\nEND;"},
+		{"loop", "LOOP\n  SELECT nonexistent FROM my_table;\n  BREAK;\nEND LOOP;"},
+		{"while", "WHILE false DO\n  SELECT nonexistent FROM my_table;\nEND WHILE;"},
+		{"repeat", "REPEAT\n  SELECT nonexistent FROM my_table;\n  UNTIL true\nEND REPEAT;"},
+		{"for in query", "FOR r IN (SELECT nonexistent FROM my_table) DO\n  SELECT 1;\nEND FOR;"},
+		{"nested blocks", "BEGIN\n  IF true THEN\n    INSERT INTO my_table (nonexistent) VALUES (1);\n  END IF;\nEND;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := l.LintSQL(tt.sql)
+			if len(results) != 1 {
+				t.Fatalf("LintSQL(%q) returned %d results, want 1: %v", tt.sql, len(results), results)
+			}
+			if !strings.Contains(results[0].Message, "nonexistent") {
+				t.Errorf("unexpected message: %s", results[0].Message)
+			}
+		})
+	}
 }
@@ -0,0 +1,542 @@
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pacer/go-bigq/bigq"
+	"github.com/pacer/go-bigq/internal/bridge"
+)
+
+// scriptLinter analyzes the statements of one script in order, carrying
+// script state such as declared variables from one statement to the next.
+type scriptLinter struct {
+	sql     string
+	catalog *bigq.Catalog
+	overlay *bigq.Overlay
+	vars    []*scriptVar
+	results []Result
+
+	// handlers counts the exception handlers enclosing the statement
+	// being linted, where the @@error variables are set.
+	handlers int
+
+	// params are the inferred parameter types already reported, as
+	// "@name TYPE" or "?position TYPE".
+	params []string
+}
+
+// scriptVar is a variable declared by DECLARE or a FOR...IN loop.
+type scriptVar struct {
+	name string
+	node *bigq.Node // declaring identifier, for unused-variable warnings
+	used bool
+}
+
+func newScriptLinter(sql string, catalog *bigq.Catalog) *scriptLinter {
+	return &scriptLinter{sql: sql, catalog: catalog, overlay: catalog.NewOverlay()}
+}
+
+// finish reports variables that were never used and releases the overlay.
+func (s *scriptLinter) finish() []Result {
+	s.dropVars(0)
+	s.overlay.Close()
+	return s.results
+}
+
+func (s *scriptLinter) errorAt(n *bigq.Node, code, msg string) {
+	s.report(n, LevelError, code, msg)
+}
+
+// report adds a result covering n.
+func (s *scriptLinter) report(n *bigq.Node, level, code, msg string) {
+	s.results = append(s.results, newResult(s.sql, n.Start, n.End, level, code, msg))
+}
+
+// analysisError reports an error from analyzing the text of n. If the
+// analyzer located it within n, the result covers the outermost node of n
+// starting there, and otherwise all of n. An unknown name gets
+// suggestions.
+func (s *scriptLinter) analysisError(n *bigq.Node, err error) {
+	start, end := n.Start, n.End
+	var status bridge.Status
+	if errors.As(err, &status) && status.ErrorOffset >= 0 && n.Start+status.ErrorOffset < n.End {
+		start = n.Start + status.ErrorOffset
+		end = nodeAt(n, start).End
+	}
+	r := newResult(s.sql, start, end, LevelError, errorCode(err), err.Error())
+	r.Suggestions = s.suggestions(n, err)
+	s.results = append(s.results, r)
+}
+
+// unlocatedError reports an error about n that did not come from analyzing
+// its text, such as one about a column type the analyzer spelled. Any
+// position in it is relative to something else, so the result covers n.
+func (s *scriptLinter) unlocatedError(n *bigq.Node, err error) {
+	s.results = append(s.results, newResult(s.sql, n.Start, n.End, LevelError, errorCode(err), err.Error()))
+}
+
+// nodeAt returns the outermost node under n that starts at offset, or the
+// innermost one containing it if none does.
+func nodeAt(n *bigq.Node, offset int) *bigq.Node {
+	at := n
+	n.Walk(func(c *bigq.Node) bool {
+		if c.Start > offset || c.End <= offset || at.Start == offset {
+			return false
+		}
+		at = c
+		return true
+	})
+	return at
+}
+
+// statement lints one statement, recursing into scripting blocks.
+func (s *scriptLinter) statement(n *bigq.Node) {
+	switch {
+	case n.Kind == "VariableDeclaration":
+		s.declare(n)
+	case n.Kind == "SingleAssignment":
+		s.assign(n)
+	case n.Kind == "AssignmentFromStruct":
+		s.assignStruct(n)
+	case n.Kind == "ForInStatement":
+		s.forIn(n)
+	case n.Kind == "BeginEndBlock":
+		mark := len(s.vars)
+		s.children(n)
+		s.dropVars(mark)
+	case n.IsSQLStatement():
+		if s.references(n) {
+			return
+		}
+		out, err := s.overlay.AnalyzeStatement(n.Text(s.sql))
+		if err != nil {
+			s.analysisError(n, err)
+			return
+		}
+		for _, table := range out.Tables {
+			if s.overlay.Dropped(table) {
+				s.errorAt(n, CodeDroppedTable, fmt.Sprintf("table %s was dropped earlier in the script", table))
+			}
+		}
+		s.applyDDL(n, out)
+		s.parameters(n, out.UndeclaredParameters)
+		switch out.StatementKind {
+		case "CreateProcedureStmt":
+			s.procedureBody(n, out.Arguments)
+		case "CallStmt":
+			s.call(n, out)
+		case "InsertStmt":
+			s.insert(n)
+		case "UpdateStmt":
+			s.update(n)
+		case "MergeStmt":
+			s.merge(n)
+		}
+	default:
+		s.children(n)
+	}
+}
+
+// children lints the statements nested in a scripting statement (IF, LOOP,
+// WHILE, REPEAT, exception handlers, ...) and records variable uses in its
+// conditions.
+func (s *scriptLinter) children(n *bigq.Node) {
+	for _, c := range n.Children {
+		switch {
+		case c.IsStatement():
+			s.statement(c)
+		case c.IsExpression():
+			s.references(c)
+		case c.Kind == "ExceptionHandler":
+			s.handlers++
+			s.children(c)
+			s.handlers--
+		default:
+			s.children(c)
+		}
+	}
+}
+
+// declare handles DECLARE name[, ...] [type] [DEFAULT expr].
+func (s *scriptLinter) declare(n *bigq.Node) {
+	var names []*bigq.Node
+	var typeNode, defaultNode *bigq.Node
+	for _, c := range n.Children {
+		switch {
+		case c.Kind == "IdentifierList":
+			names = c.Children
+		case c.IsType():
+			typeNode = c
+		case c.IsExpression():
+			defaultNode = c
+		}
+	}
+
+	// The variables are declared even if their type or DEFAULT is bad, so
+	// that the statements using them are not reported as well. If the
+	// type cannot be told, it is left unknown.
+	var typeName string
+	unknown := defaultNode != nil && s.references(defaultNode)
+	switch {
+	case typeNode != nil:
+		typeName = typeNode.Text(s.sql)
+		if _, err := bigq.ParseType(typeName); err != nil {
+			s.analysisError(typeNode, err)
+			typeName = ""
+		} else if defaultNode != nil && !unknown {
+			if err := s.overlay.CheckAssignment(defaultNode.Text(s.sql), typeName); err != nil {
+				s.analysisError(defaultNode, err)
+			}
+		}
+	case defaultNode != nil && !unknown:
+		t, err := s.overlay.AnalyzeExpression(defaultNode.Text(s.sql))
+		if err != nil {
+			s.analysisError(defaultNode, err)
+			break
+		}
+		typeName = t
+	}
+
+	for _, id := range names {
+		s.declareVar(id, typeName, false)
+	}
+}
+
+func (s *scriptLinter) declareVar(id *bigq.Node, typeName string, used bool) {
+	if err := s.overlay.DeclareVariable(id.Name, typeName); err != nil {
+		s.unlocatedError(id, err)
+		return
+	}
+	s.vars = append(s.vars, &scriptVar{name: id.Name, node: id, used: used})
+}
+
+// assign handles SET name = expr.
+func (s *scriptLinter) assign(n *bigq.Node) {
+	var target, value *bigq.Node
+	for _, c := range n.Children {
+		switch {
+		case c.Kind == "Identifier":
+			target = c
+		case c.IsExpression():
+			value = c
+		}
+	}
+	if target == nil || value == nil {
+		return
+	}
+	unknown := s.references(value)
+
+	typeName, ok := s.overlay.VariableType(target.Name)
+	if !ok {
+		s.errorAt(target, CodeUndeclaredVariable, fmt.Sprintf("assignment to undeclared variable %s", target.Name))
+		return
+	}
+	if unknown || typeName == "" {
+		return
+	}
+	if err := s.overlay.CheckAssignment(value.Text(s.sql), typeName); err != nil {
+		s.analysisError(value, err)
+	}
+}
+
+// assignStruct handles SET (a, b) = expr. Only the targets and the
+// expression itself are checked; field-by-field coercion is left to
+// BigQuery.
+func (s *scriptLinter) assignStruct(n *bigq.Node) {
+	for _, c := range n.Children {
+		switch {
+		case c.Kind == "IdentifierList":
+			for _, id := range c.Children {
+				if _, ok := s.overlay.VariableType(id.Name); !ok {
+					s.errorAt(id, CodeUndeclaredVariable, fmt.Sprintf("assignment to undeclared variable %s", id.Name))
+				}
+			}
+		case c.IsExpression():
+			if s.references(c) {
+				continue
+			}
+			if _, err := s.overlay.AnalyzeExpression(c.Text(s.sql)); err != nil {
+				s.analysisError(c, err)
+			}
+		}
+	}
+}
+
+// forIn handles FOR name IN (query) DO ... END FOR. The loop variable is a
+// STRUCT of the query's output columns, visible only inside the body.
+func (s *scriptLinter) forIn(n *bigq.Node) {
+	mark := len(s.vars)
+	var loopVar *bigq.Node
+	for _, c := range n.Children {
+		switch {
+		case c.Kind == "Identifier":
+			loopVar = c
+		case c.Kind == "Query":
+			var typeName string
+			if !s.references(c) {
+				out, err := s.overlay.AnalyzeStatement(c.Text(s.sql))
+				if err != nil {
+					s.analysisError(c, err)
+				} else {
+					typeName = structType(out.OutputColumns)
+				}
+			}
+			if loopVar != nil {
+				s.declareVar(loopVar, typeName, true)
+			}
+		case c.IsStatement():
+			s.statement(c)
+		default:
+			s.children(c)
+		}
+	}
+	s.dropVars(mark)
+}
+
+// applyDDL updates the script's view of the catalog after a CREATE TABLE,
+// CREATE VIEW, CREATE FUNCTION, CREATE PROCEDURE, DROP or ALTER TABLE
+// statement, so that later statements see the tables and routines the
+// script has built.
+func (s *scriptLinter) applyDDL(n *bigq.Node, out *bigq.AnalyzeOutput) {
+	name := strings.Join(out.NamePath, ".")
+	switch out.StatementKind {
+	case "CreateTableStmt", "CreateTableAsSelectStmt", "CreateExternalTableStmt", "CreateViewStmt", "CreateMaterializedViewStmt":
+		if err := s.overlay.AddTable(name, out.ColumnDefinitions); err != nil {
+			s.unlocatedError(n, err)
+		}
+	case "CreateFunctionStmt", "CreateTableFunctionStmt", "CreateProcedureStmt":
+		if err := s.overlay.AddRoutine(n.Text(s.sql)); err != nil {
+			s.analysisError(n, err)
+		}
+	case "DropStmt":
+		if strings.HasSuffix(out.ObjectType, "TABLE") || strings.HasSuffix(out.ObjectType, "VIEW") {
+			s.overlay.DropTable(name)
+		}
+	case "AlterTableStmt":
+		columns, ok := s.overlay.Table(name)
+		if !ok {
+			return
+		}
+		columns, err := alterColumns(columns, out.AlterActions)
+		if err == nil {
+			err = s.overlay.AddTable(name, columns)
+		}
+		if err != nil {
+			s.unlocatedError(n, err)
+		}
+	}
+
+	// A changed or dropped table may break a routine that reads it, which
+	// is reported here, once.
+	for _, err := range s.overlay.RoutineErrors() {
+		s.report(n, LevelWarning, errorCode(err), err.Error())
+	}
+}
+
+// procedureBody lints the body of a CREATE PROCEDURE statement, which the
+// analyzer leaves alone, with the procedure's arguments in scope as
+// variables. The body runs on its own when the procedure is called, so it
+// is linted by a linter of its own that sees none of the script's
+// variables, temporary tables or exception handlers.
+func (s *scriptLinter) procedureBody(n *bigq.Node, args []bigq.Argument) {
+	body := newScriptLinter(s.sql, s.catalog)
+	body.params = s.params
+	var params []*bigq.Node
+	for _, c := range n.Children {
+		switch c.Kind {
+		case "FunctionParameters":
+			for _, p := range c.Children {
+				for _, id := range p.Children {
+					if id.Kind == "Identifier" {
+						params = append(params, id)
+						break
+					}
+				}
+			}
+		case "Script":
+			for i, a := range args {
+				// Templated arguments have no type to declare.
+				if i < len(params) && a.TypeName != "ANY TYPE" {
+					body.declareVar(params[i], a.TypeName, true)
+				}
+			}
+			body.children(c)
+		}
+	}
+	body.dropVars(0)
+	s.results = append(s.results, body.results...)
+	s.params = body.params
+}
+
+// call checks the OUT and INOUT arguments of a CALL statement, which must
+// be variables able to hold what the procedure assigns to them. The
+// analyzer has already checked the argument count and types.
+func (s *scriptLinter) call(n *bigq.Node, out *bigq.AnalyzeOutput) {
+	proc, ok := s.overlay.Procedure(strings.Join(out.NamePath, "."))
+	if !ok {
+		return
+	}
+	var args []*bigq.Node
+	for _, c := range n.Children {
+		if c.Kind == "TVFArgument" && len(c.Children) > 0 {
+			args = append(args, c.Children[0])
+		}
+	}
+	for i, a := range proc.Arguments {
+		if a.Mode == "IN" || i >= len(args) {
+			continue
+		}
+		arg := args[i]
+		path := arg.Path()
+		var typeName string
+		if len(path) == 1 {
+			typeName, ok = s.overlay.VariableType(path[0])
+		}
+		if len(path) != 1 || !ok {
+			s.errorAt(arg, CodeProcedureArgument, fmt.Sprintf("argument %s of procedure %s is %s and must be a variable", a.Name, proc.Name, a.Mode))
+			continue
+		}
+		if a.TypeName == "ANY TYPE" {
+			continue
+		}
+		if err := s.overlay.CheckAssignment("CAST(NULL AS "+a.TypeName+")", typeName); err != nil {
+			s.errorAt(arg, CodeProcedureArgument, fmt.Sprintf("variable %s of type %s cannot hold %s argument %s of procedure %s, of type %s",
+				path[0], typeName, a.Mode, a.Name, proc.Name, a.TypeName))
+		}
+	}
+}
+
+// parameters reports the inferred types of the undeclared query parameters
+// a statement uses, at their first use, once per script for each name and
+// type.
+func (s *scriptLinter) parameters(n *bigq.Node, params []bigq.Parameter) {
+	if len(params) == 0 {
+		return
+	}
+	uses := n.Find("ParameterExpr")
+	for _, p := range params {
+		name := "@" + p.Name
+		if p.Name == "" {
+			name = fmt.Sprintf("?%d", p.Position)
+		}
+		key := name + " " + p.TypeName
+		if slices.Contains(s.params, key) {
+			continue
+		}
+		s.params = append(s.params, key)
+
+		at, position := n, 0
+		for _, u := range uses {
+			if len(u.Children) == 0 {
+				position++
+			}
+			if p.Name == "" && len(u.Children) == 0 && position == p.Position ||
+				p.Name != "" && len(u.Children) > 0 && strings.EqualFold(u.Children[0].Name, p.Name) {
+				at = u
+				break
+			}
+		}
+		s.report(at, LevelNotice, CodeInferredParameter, fmt.Sprintf("parameter %s has inferred type %s", name, p.TypeName))
+	}
+}
+
+// alterColumns returns a copy of columns with ALTER TABLE actions applied.
+func alterColumns(columns []bigq.ColumnDef, actions []bigq.AlterAction) ([]bigq.ColumnDef, error) {
+	columns = slices.Clone(columns)
+	for _, a := range actions {
+		i := slices.IndexFunc(columns, func(c bigq.ColumnDef) bool {
+			return strings.EqualFold(c.Name, a.Column)
+		})
+		if a.Kind == "AddColumnAction" {
+			switch {
+			case i >= 0 && a.IfExists:
+			case i >= 0:
+				return nil, fmt.Errorf("column %s already exists", a.Column)
+			default:
+				columns = append(columns, bigq.ColumnDef{Name: a.Column, TypeName: a.TypeName, Mode: a.Mode})
+			}
+			continue
+		}
+		if i < 0 && a.IfExists {
+			continue
+		}
+		if i < 0 {
+			return nil, fmt.Errorf("column %s not found", a.Column)
+		}
+		switch a.Kind {
+		case "DropColumnAction":
+			columns = slices.Delete(columns, i, i+1)
+		case "RenameColumnAction":
+			columns[i].Name = a.NewName
+		case "AlterColumnSetDataTypeAction":
+			columns[i].TypeName = a.TypeName
+		}
+	}
+	return columns, nil
+}
+
+// structType returns the STRUCT type of a row with the given columns.
+func structType(columns []bigq.ColumnDef) string {
+	fields := make([]string, len(columns))
+	for i, c := range columns {
+		fields[i] = c.Name + " " + c.TypeName
+	}
+	return "STRUCT<" + strings.Join(fields, ", ") + ">"
+}
+
+// dropVars removes the variables declared after mark, warning about any
+// that were never used.
+func (s *scriptLinter) dropVars(mark int) {
+	for _, v := range s.vars[mark:] {
+		if !v.used {
+			s.report(v.node, LevelWarning, CodeUnusedVariable, fmt.Sprintf("variable %s is declared but never used", v.name))
+		}
+		s.overlay.DropVariable(v.name)
+	}
+	s.vars = s.vars[:mark]
+}
+
+// references records what the statement or expression n refers to: it
+// marks the variables referenced anywhere under n as used, a variable
+// reference being a path expression whose first name matches it, and
+// reports @@error system variables outside an exception handler. Exception
+// handlers under n are left to children, and the body of a CREATE
+// PROCEDURE to procedureBody.
+//
+// It reports whether n refers to a variable of unknown type. The analyzer
+// cannot resolve those, so n is not worth analyzing: the error that left
+// the type unknown has been reported already.
+func (s *scriptLinter) references(n *bigq.Node) (unknown bool) {
+	n.Walk(func(c *bigq.Node) bool {
+		switch c.Kind {
+		case "ExceptionHandler", "Script":
+			return false
+		case "SystemVariableExpr":
+			if s.handlers == 0 && len(c.Children) > 0 && isErrorVariable(c.Children[0].Path()) {
+				s.errorAt(c, CodeErrorVariable, "@@error is only set inside an EXCEPTION WHEN ERROR THEN handler")
+			}
+			return false
+		}
+		if path := c.Path(); len(path) > 0 {
+			for _, v := range s.vars {
+				if strings.EqualFold(v.name, path[0]) {
+					v.used = true
+					if t, _ := s.overlay.VariableType(v.name); t == "" {
+						unknown = true
+					}
+				}
+			}
+		}
+		return true
+	})
+	return unknown
+}
+
+// isErrorVariable reports whether path, a system variable name without its
+// @@, names one of the @@error variables.
+func isErrorVariable(path []string) bool {
+	return len(path) > 0 && strings.EqualFold(path[0], "error")
+}
@@ -0,0 +1,304 @@
+package lint
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pacer/go-bigq/bigq"
+	"github.com/pacer/go-bigq/internal/bridge"
+)
+
+func TestLintSQL_ScriptVariables(t *testing.T) {
+	l := New(newTestCatalog(t))
+
+	tests := []struct {
+		name     string
+		sql      string
+		errors   int
+		warnings int
+	}{
+		{"declared type", "DECLARE run_date DATE DEFAULT CURRENT_DATE();\nSELECT id FROM my_table WHERE DATE '2024-01-01' = run_date;", 0, 0},
+		{"inferred type", "DECLARE n DEFAULT 5;\nSELECT name FROM my_table WHERE id = n;", 0, 0},
+		{"multiple names", "DECLARE a, b INT64 DEFAULT 0;\nSELECT a + b;", 0, 0},
+		{"uses earlier variable", "DECLARE a INT64 DEFAULT 1;\nDECLARE b DEFAULT a + 1;\nSELECT b;", 0, 0},
+		{"set compatible", "DECLARE n INT64;\nSET n = (SELECT MAX(id) FROM my_table);\nSELECT n;", 0, 0},
+		{"set coercible", "DECLARE f FLOAT64;\nSET f = 1;\nSELECT f;", 0, 0},
+		{"set type mismatch", "DECLARE n INT64;\nSET n = 'abc';\nSELECT n;", 1, 0},
+		{"default type mismatch", "DECLARE d DATE DEFAULT 'x' || 'y';\nSELECT d;", 1, 0},
+		{"set undeclared", "SET missing = 1;", 1, 0},
+		{"redeclared", "DECLARE x INT64;\nDECLARE x STRING;\nSELECT x;", 1, 0},
+		{"unused", "DECLARE unused INT64;\nSELECT 1;", 0, 1},
+		{"used only in condition", "DECLARE x INT64 DEFAULT 1;\nIF x > 0 THEN\n  SELECT 1;\nEND IF;", 0, 0},
+		{"for loop variable", "FOR r IN (SELECT id, name FROM my_table) DO\n  SELECT r.id, r.name;\nEND FOR;", 0, 0},
+		{"bad default", "DECLARE n DEFAULT nonexistent + 1;\nSELECT name FROM my_table WHERE id = n;\nSET n = 2;", 1, 0},
+		{"bad default with type", "DECLARE n INT64 DEFAULT nonexistent;\nSELECT name FROM my_table WHERE id = n;", 1, 0},
+		{"bad type", "DECLARE n NOTATYPE;\nSELECT name FROM my_table WHERE id = n;", 1, 0},
+		{"bad for query", "FOR r IN (SELECT nonexistent FROM my_table) DO\n  SELECT r.id;\nEND FOR;", 1, 0},
+		{"block scope", "BEGIN\n  DECLARE x INT64 DEFAULT 1;\n  SELECT x;\nEND;\nSELECT x;", 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errors, warnings int
+			results := l.LintSQL(tt.sql)
+			for _, r := range results {
+				switch r.Level {
+				case LevelError:
+					errors++
+				case LevelWarning:
+					warnings++
+				}
+			}
+			if errors != tt.errors || warnings != tt.warnings {
+				t.Errorf("LintSQL(%q) = %d errors, %d warnings, want %d, %d", tt.sql, errors, warnings, tt.errors, tt.warnings)
+				for _, r := range results {
+					t.Logf("  %s", r)
+				}
+			}
+		})
+	}
+}
+
+func TestLintSQL_ScriptTables(t *testing.T) {
+	l := New(newTestCatalog(t))
+
+	tests := []struct {
+		name   string
+		sql    string
+		errors int
+	}{
+		{"temp table with columns", "CREATE TEMP TABLE staging (id INT64, label STRING);\nSELECT id, label FROM staging;", 0},
+		{"temp table as select", "CREATE TEMP TABLE staging AS SELECT id, UPPER(name) AS upper_name FROM my_table;\nSELECT upper_name FROM staging;", 0},
+		{"ctas unknown column", "CREATE TEMP TABLE staging AS SELECT id FROM my_table;\nSELECT name FROM staging;", 1},
+		{"create table", "CREATE TABLE summary (total INT64);\nINSERT INTO summary (total) SELECT COUNT(*) FROM my_table;", 0},
+		{"create qualified table", "CREATE TABLE dataset.summary (total INT64);\nSELECT total FROM `dataset.summary`;", 0},
+		{"create view", "CREATE VIEW v AS SELECT id FROM my_table;\nSELECT id FROM v;", 0},
+		{"before create", "SELECT id FROM staging;\nCREATE TEMP TABLE staging (id INT64);", 1},
+		{"inside block", "BEGIN\n  CREATE TEMP TABLE staging (id INT64);\n  IF true THEN\n    SELECT id FROM staging;\n  END IF;\nEND;", 0},
+		{"drop temp table", "CREATE TEMP TABLE staging (id INT64);\nDROP TABLE staging;\nSELECT id FROM staging;", 1},
+		{"drop base table", "DROP TABLE my_table;\nSELECT id FROM my_table;", 1},
+		{"drop and recreate", "DROP TABLE my_table;\nCREATE TABLE my_table (other INT64);\nSELECT other FROM my_table;", 0},
+		{"add column", "ALTER TABLE my_table ADD COLUMN extra STRING;\nSELECT extra FROM my_table;", 0},
+		{"add existing column", "ALTER TABLE my_table ADD COLUMN name STRING;", 1},
+		{"add column if not exists", "ALTER TABLE my_table ADD COLUMN IF NOT EXISTS name STRING;\nSELECT name FROM my_table;", 0},
+		{"drop column if exists", "ALTER TABLE my_table DROP COLUMN IF EXISTS missing;\nSELECT id, name FROM my_table;", 0},
+		{"drop column", "ALTER TABLE my_table DROP COLUMN name;\nSELECT name FROM my_table;", 1},
+		{"rename column", "ALTER TABLE my_table RENAME COLUMN name TO full_name;\nSELECT full_name FROM my_table;", 0},
+		{"temp function", "CREATE TEMP FUNCTION twice(x INT64) AS (x * 2);\nSELECT twice(id) FROM my_table;", 0},
+		{"temp function wrong argument", "CREATE TEMP FUNCTION twice(x INT64) AS (x * 2);\nSELECT twice(name) FROM my_table;", 1},
+		{"temp function bad body", "CREATE TEMP FUNCTION f(x INT64) AS (x || 'a');", 1},
+		{"temp function named like builtin", "CREATE TEMP FUNCTION concat(x STRING) AS (x);", 1},
+		{"function before create", "SELECT twice(1);\nCREATE TEMP FUNCTION twice(x INT64) AS (x * 2);", 1},
+		{"table function", "CREATE TABLE FUNCTION ds.named(n STRING) AS SELECT id FROM my_table WHERE name = n;\nSELECT id FROM ds.named('a');", 0},
+		{"table function wrong column", "CREATE TABLE FUNCTION ds.named(n STRING) AS SELECT id FROM my_table WHERE name = n;\nSELECT name FROM ds.named('a');", 1},
+		{"alter temp table", "CREATE TEMP TABLE staging (id INT64);\nALTER TABLE staging ADD COLUMN note STRING;\nSELECT id, note FROM staging;", 0},
+		{"alter table a function reads", "CREATE TEMP TABLE staging (id INT64);\nCREATE TEMP FUNCTION n() AS ((SELECT COUNT(id) FROM staging));\nALTER TABLE staging ADD COLUMN note STRING;\nSELECT n(), note FROM staging;", 0},
+		{"replace table a function reads", "CREATE TEMP TABLE staging (id INT64);\nCREATE TEMP FUNCTION n() AS ((SELECT COUNT(id) FROM staging));\nCREATE OR REPLACE TEMP TABLE staging (id INT64, note STRING);\nSELECT n(), note FROM staging;", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := l.LintSQL(tt.sql)
+			if len(results) != tt.errors {
+				t.Errorf("LintSQL(%q) returned %d results, want %d", tt.sql, len(results), tt.errors)
+				for _, r := range results {
+					t.Logf("  %s", r)
+				}
+			}
+		})
+	}
+}
+
+func TestLintSQL_BrokenRoutine(t *testing.T) {
+	l := New(newTestCatalog(t))
+
+	// Dropping the table f reads breaks f, which is reported at the DROP;
+	// statements that do not call f are unaffected, and calls of f find
+	// no function.
+	sql := "CREATE TEMP TABLE tmp (id INT64);\n" +
+		"CREATE TEMP FUNCTION f() AS ((SELECT COUNT(*) FROM tmp));\n" +
+		"DROP TABLE tmp;\n" +
+		"DECLARE n INT64 DEFAULT 1;\n" +
+		"SELECT n, id FROM my_table;\n" +
+		"SELECT f();\n"
+	results := l.LintSQL(sql)
+	if len(results) != 2 {
+		t.Fatalf("LintSQL returned %d results, want 2: %v", len(results), results)
+	}
+	if r := results[0]; r.Line != 3 || r.Level != LevelWarning || r.Code != string(bigq.KindUnknownTable) || !strings.Contains(r.Message, "f ") {
+		t.Errorf("first result = %s, want a warning about f at the DROP", r)
+	}
+	if r := results[1]; r.Line != 6 || r.Code != string(bigq.KindUnknownFunction) {
+		t.Errorf("second result = %s, want an unknown function at the call", r)
+	}
+}
+
+func TestLintSQL_Procedures(t *testing.T) {
+	l := New(newTestCatalog(t))
+
+	const proc = "CREATE PROCEDURE ds.count_named(IN n STRING, OUT total INT64, INOUT seen INT64)\n" +
+		"BEGIN\n  SET total = (SELECT COUNT(*) FROM my_table WHERE name = n);\n  SET seen = seen + 1;\nEND;\n"
+
+	tests := []struct {
+		name   string
+		sql    string
+		errors int
+	}{
+		{"call", proc + "DECLARE total, seen INT64 DEFAULT 0;\nCALL ds.count_named('a', total, seen);\nSELECT total, seen;", 0},
+		{"call quoted path", proc + "DECLARE total, seen INT64 DEFAULT 0;\nCALL `ds.count_named`('a', total, seen);\nSELECT total, seen;", 0},
+		{"unknown procedure", "CALL ds.nothing(1);", 1},
+		{"argument count", proc + "DECLARE total INT64;\nCALL ds.count_named('a', total);\nSELECT total;", 1},
+		{"argument type", proc + "DECLARE total, seen INT64 DEFAULT 0;\nCALL ds.count_named(1, total, seen);\nSELECT total, seen;", 1},
+		{"out literal", proc + "DECLARE seen INT64 DEFAULT 0;\nCALL ds.count_named('a', 1, seen);\nSELECT seen;", 1},
+		{"out undeclared", proc + "DECLARE seen INT64 DEFAULT 0;\nCALL ds.count_named('a', missing, seen);\nSELECT seen;", 1},
+		{"out wrong type", proc + "DECLARE total STRING;\nDECLARE seen INT64 DEFAULT 0;\nCALL ds.count_named('a', total, seen);\nSELECT total, seen;", 1},
+		{"body error", "CREATE PROCEDURE ds.p(n INT64)\nBEGIN\n  SELECT missing FROM my_table WHERE id = n;\nEND;", 1},
+		{"body undeclared assignment", "CREATE PROCEDURE ds.p()\nBEGIN\n  SET x = 1;\nEND;", 1},
+		{"body sees no script variables", "DECLARE x INT64 DEFAULT 1;\nCREATE PROCEDURE ds.p()\nBEGIN\n  SELECT x;\nEND;\nSELECT x;", 1},
+		{"body sees no temp tables", "CREATE TEMP TABLE staging (id INT64);\nCREATE PROCEDURE ds.p()\nBEGIN\n  SELECT id FROM staging;\nEND;\nSELECT id FROM staging;", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := l.LintSQL(tt.sql)
+			if len(results) != tt.errors {
+				t.Errorf("LintSQL(%q) returned %d results, want %d", tt.sql, len(results), tt.errors)
+				for _, r := range results {
+					t.Logf("  %s", r)
+				}
+			}
+		})
+	}
+}
+
+func TestLintSQL_RequiredColumns(t *testing.T) {
+	cat := newTestCatalog(t)
+	err := cat.AddTable("accounts", []bigq.ColumnDef{
+		{Name: "id", TypeName: "INT64", Mode: "REQUIRED"},
+		{Name: "email", TypeName: "STRING", Mode: "REQUIRED"},
+		{Name: "note", TypeName: "STRING"},
+	})
+	if err != nil {
+		t.Fatalf("AddTable: %v", err)
+	}
+	l := New(cat)
+
+	tests := []struct {
+		name   string
+		sql    string
+		errors int
+	}{
+		{"all columns", "INSERT INTO accounts (id, email, note) VALUES (1, 'a@example.com', NULL);", 0},
+		{"nullable omitted", "INSERT INTO accounts (id, email) VALUES (1, 'a@example.com');", 0},
+		{"required omitted", "INSERT INTO accounts (id, note) VALUES (1, 'x');", 1},
+		{"null into required", "INSERT INTO accounts (id, email) VALUES (1, NULL), (2, 'b@example.com');", 1},
+		{"null without column list", "INSERT INTO accounts VALUES (NULL, 'a@example.com', 'x');", 1},
+		{"null from select", "INSERT INTO accounts (email, id) SELECT name, NULL FROM my_table;", 1},
+		{"select star", "INSERT INTO accounts (id, email) SELECT * FROM (SELECT 1 AS id, 'a' AS email);", 0},
+		{"update to null", "UPDATE accounts SET email = NULL WHERE id = 1;", 1},
+		{"added required column omitted", "ALTER TABLE accounts ADD COLUMN code STRING(3) NOT NULL;\nINSERT INTO accounts (id, email) VALUES (1, 'a@example.com');", 1},
+		{"merge insert", "MERGE accounts a USING my_table m ON a.id = m.id\nWHEN NOT MATCHED THEN INSERT (id, email) VALUES (m.id, m.name);", 0},
+		{"merge insert omits required", "MERGE accounts a USING my_table m ON a.id = m.id\nWHEN NOT MATCHED THEN INSERT (id, note) VALUES (m.id, m.name);", 1},
+		{"merge insert null", "MERGE accounts a USING my_table m ON a.id = m.id\nWHEN NOT MATCHED THEN INSERT VALUES (m.id, NULL, m.name);", 1},
+		{"merge insert row", "MERGE accounts a USING (SELECT 1 AS id, 'a' AS email, 'x' AS note) m ON a.id = m.id\nWHEN NOT MATCHED THEN INSERT ROW;", 0},
+		{"merge update to null", "MERGE accounts a USING my_table m ON a.id = m.id\nWHEN MATCHED THEN UPDATE SET email = NULL;", 1},
+		{"update nullable to null", "UPDATE accounts SET note = NULL WHERE id = 1;", 0},
+		{"not null temp table", "CREATE TEMP TABLE staging (id INT64 NOT NULL, label STRING);\nINSERT INTO staging (label) VALUES ('x');", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := l.LintSQL(tt.sql)
+			if len(results) != tt.errors {
+				t.Errorf("LintSQL(%q) returned %d results, want %d", tt.sql, len(results), tt.errors)
+				for _, r := range results {
+					t.Logf("  %s", r)
+				}
+			}
+		})
+	}
+}
+
+func TestLintSQL_InferredParameters(t *testing.T) {
+	cat, err := bigq.NewCatalog("test", bigq.WithUndeclaredParameters())
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	t.Cleanup(cat.Close)
+	if err := cat.AddTable("my_table", []bigq.ColumnDef{{Name: "id", TypeName: "INT64"}, {Name: "name", TypeName: "STRING"}}); err != nil {
+		t.Fatalf("AddTable: %v", err)
+	}
+
+	sql := "SELECT id FROM my_table WHERE name = @name;\nSELECT id FROM my_table WHERE name = @name AND id > @min_id;\n"
+	results := New(cat).LintSQL(sql)
+	want := []Result{
+		{Line: 1, Column: 38, EndLine: 1, EndColumn: 43, Offset: 37, EndOffset: 42, Level: LevelNotice, Code: CodeInferredParameter, Message: "parameter @name has inferred type STRING"},
+		{Line: 2, Column: 53, EndLine: 2, EndColumn: 60, Offset: 96, EndOffset: 103, Level: LevelNotice, Code: CodeInferredParameter, Message: "parameter @min_id has inferred type INT64"},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("LintSQL = %v, want %v", results, want)
+	}
+}
+
+func TestLintSQL_SystemVariables(t *testing.T) {
+	l := New(newTestCatalog(t))
+
+	tests := []struct {
+		name   string
+		sql    string
+		errors int
+	}{
+		{"project and dataset", "SELECT id FROM my_table WHERE name = CONCAT(@@project_id, '.', @@dataset_id);", 0},
+		{"script variables", "DECLARE n INT64 DEFAULT @@row_count;\nSELECT n, @@script.job_id;", 0},
+		{"error in handler", "BEGIN\n  SELECT 1 / 0;\nEXCEPTION WHEN ERROR THEN\n  SELECT @@error.message, @@error.statement_text;\nEND;", 0},
+		{"error in handler assignment", "DECLARE msg STRING;\nBEGIN\n  SELECT 1;\nEXCEPTION WHEN ERROR THEN\n  SET msg = @@error.message;\nEND;\nSELECT msg;", 0},
+		{"error in nested block", "BEGIN\n  SELECT 1;\nEXCEPTION WHEN ERROR THEN\n  IF @@error.message LIKE '%x%' THEN\n    SELECT @@error.formatted_stack_trace;\n  END IF;\nEND;", 0},
+		{"error outside handler", "SELECT @@error.message;", 1},
+		{"error in block body", "BEGIN\n  SELECT @@error.message;\nEXCEPTION WHEN ERROR THEN\n  SELECT 1;\nEND;", 1},
+		{"error in procedure handler", "CREATE PROCEDURE ds.p()\nBEGIN\n  SELECT 1;\nEXCEPTION WHEN ERROR THEN\n  SELECT @@error.message;\nEND;", 0},
+		{"error in procedure body", "CREATE PROCEDURE ds.p()\nBEGIN\n  SELECT @@error.message;\nEND;", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := l.LintSQL(tt.sql)
+			if len(results) != tt.errors {
+				t.Errorf("LintSQL(%q) returned %d results, want %d", tt.sql, len(results), tt.errors)
+				for _, r := range results {
+					t.Logf("  %s", r)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalysisErrorPosition(t *testing.T) {
+	const sql = "SELECT id FROM my_table;"
+	n := &bigq.Node{Kind: "QueryStatement", Start: 0, End: len(sql) - 1}
+	tests := []struct {
+		name       string
+		offset     int
+		wantOffset int
+	}{
+		{"inside the node", 7, 7},
+		{"outside the node", 40, 0},
+		{"no offset", -1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScriptLinter(sql, newTestCatalog(t))
+			defer s.finish()
+			status := bridge.Status{ErrorMessage: "boom", ErrorLine: 1, ErrorColumn: tt.offset + 1, ErrorOffset: tt.offset}
+			s.analysisError(n, fmt.Errorf("analysis error: %w", status))
+			if len(s.results) != 1 {
+				t.Fatalf("results = %v, want one", s.results)
+			}
+			r := s.results[0]
+			if r.Offset != tt.wantOffset || strings.Contains(r.Message, fmt.Sprintf("%d:%d", status.ErrorLine, status.ErrorColumn)) || !strings.Contains(r.Message, "boom") {
+				t.Errorf("result = %+v, want offset %d and the message without its position", r, tt.wantOffset)
+			}
+		})
+	}
+}
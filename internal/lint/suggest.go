@@ -0,0 +1,244 @@
+package lint
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pacer/go-bigq/bigq"
+)
+
+// maxSuggestions caps the names suggested for one result.
+const maxSuggestions = 3
+
+var keywords = sync.OnceValue(bigq.Keywords)
+
+// suggestions returns the names that may have been meant by the unknown
+// table, column or function an analysis error of n is about: the closest
+// names the script can see to the path the error points at. An unknown
+// field or procedure gets none.
+func (s *scriptLinter) suggestions(n *bigq.Node, err error) []string {
+	var e *bigq.Error
+	if !errors.As(err, &e) || e.Offset < 0 || n.Start+e.Offset >= n.End {
+		return nil
+	}
+	path := pathAt(n, n.Start+e.Offset)
+	if path == nil {
+		return nil
+	}
+	name := strings.Join(path.Path(), ".")
+
+	switch e.Kind {
+	case bigq.KindUnknownTable:
+		return closest(name, trailingParts(s.overlay.TableNames(), strings.Count(name, ".")+1))
+	case bigq.KindUnknownColumn:
+		// The error is about the first name of the path; the rest are
+		// fields, which it would point at instead.
+		return closest(path.Path()[0], s.namesInScope(n))
+	case bigq.KindUnknownFunction:
+		if inCall(path) {
+			return nil
+		}
+		return s.functionCase(name, closest(name, s.overlay.FunctionNames()))
+	}
+	return nil
+}
+
+// pathAt returns the outermost PathExpression under n that starts at
+// offset, or nil if there is none.
+func pathAt(n *bigq.Node, offset int) *bigq.Node {
+	var path *bigq.Node
+	n.Walk(func(c *bigq.Node) bool {
+		if path != nil || c.Start > offset || c.End <= offset {
+			return false
+		}
+		if c.Kind == "PathExpression" && c.Start == offset {
+			path = c
+			return false
+		}
+		return true
+	})
+	return path
+}
+
+// inCall reports whether n is part of a CALL statement, where an unknown
+// function is a procedure.
+func inCall(n *bigq.Node) bool {
+	for ; n != nil; n = n.Parent {
+		if n.Kind == "CallStatement" {
+			return true
+		}
+	}
+	return false
+}
+
+// namesInScope returns the names a bare identifier in n can refer to: the
+// columns of the tables n reads and the script's variables.
+func (s *scriptLinter) namesInScope(n *bigq.Node) []string {
+	var names []string
+	for _, ref := range n.Find("TablePathExpression") {
+		path := childOfKind(ref, "PathExpression")
+		if path == nil {
+			continue
+		}
+		columns, _ := s.overlay.Table(strings.Join(path.Path(), "."))
+		for _, c := range columns {
+			names = append(names, c.Name)
+		}
+	}
+	for _, v := range s.vars {
+		names = append(names, v.name)
+	}
+	return names
+}
+
+// keywordSuggestions returns the keywords that may have been meant by the
+// word a syntax error points at, in the word's case. Words that are
+// keywords themselves, or not words, get none.
+func keywordSuggestions(word string) []string {
+	r, _ := utf8.DecodeRuneInString(word)
+	if r != '_' && !unicode.IsLetter(r) {
+		return nil
+	}
+	if slices.ContainsFunc(keywords(), func(k string) bool { return strings.EqualFold(k, word) }) {
+		return nil
+	}
+	var names []string
+	for _, k := range closest(word, keywords()) {
+		names = append(names, matchCase(word, k))
+	}
+	return names
+}
+
+// functionCase returns the function names in names in the case to suggest
+// them in for a call written as word: builtin functions, whose names are
+// case-insensitive, in the case of word, and the others as they were
+// defined.
+func (s *scriptLinter) functionCase(word string, names []string) []string {
+	builtins := s.catalog.BuiltinFunctionNames()
+	functions := s.overlay.FunctionNames()
+	out := make([]string, len(names))
+	for i, name := range names {
+		if slices.Contains(builtins, strings.ToLower(name)) {
+			out[i] = matchCase(word, name)
+			continue
+		}
+		out[i] = name
+		if j := slices.IndexFunc(functions, func(f string) bool { return strings.EqualFold(f, name) }); j >= 0 {
+			out[i] = functions[j]
+		}
+	}
+	return out
+}
+
+// trailingParts returns names cut down to their last n dot-separated
+// parts, so that they compare with a name written with n parts, e.g.
+// under the default dataset. Names with fewer parts are left out.
+func trailingParts(names []string, n int) []string {
+	var out []string
+	for _, name := range names {
+		parts := strings.Split(name, ".")
+		if len(parts) >= n {
+			out = append(out, strings.Join(parts[len(parts)-n:], "."))
+		}
+	}
+	return out
+}
+
+// closest returns up to maxSuggestions candidates within a third of
+// name's length in edit distance from it, ignoring case, nearest first.
+// On a tie, candidates as long as name come first, since a typo is more
+// often a wrong letter than a missing or extra one. Candidates equal to
+// name are not suggestions.
+func closest(name string, candidates []string) []string {
+	type match struct {
+		name     string
+		distance int
+		length   int // difference in length from name
+	}
+	size := utf8.RuneCountInString(name)
+	limit := size / 3
+	var matches []match
+	for _, c := range candidates {
+		d := editDistance(name, c)
+		if d == 0 || d > limit {
+			continue
+		}
+		matches = append(matches, match{c, d, abs(utf8.RuneCountInString(c) - size)})
+	}
+	slices.SortStableFunc(matches, func(a, b match) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		if a.length != b.length {
+			return a.length - b.length
+		}
+		return strings.Compare(strings.ToLower(a.name), strings.ToLower(b.name))
+	})
+	var out []string
+	for _, m := range matches {
+		out = append(out, m.name)
+	}
+	return dedupe(out)
+}
+
+// editDistance returns the number of single-character insertions,
+// deletions, substitutions and transpositions of adjacent characters that
+// turn a into b, ignoring case.
+func editDistance(a, b string) int {
+	s, t := []rune(strings.ToLower(a)), []rune(strings.ToLower(b))
+	// d[i][j] is the distance between s[:i] and t[:j].
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+// matchCase upper-cases name if word is written in upper case, and
+// lower-cases it otherwise, for keywords and builtin function names, which
+// are case-insensitive.
+func matchCase(word, name string) string {
+	if word == strings.ToUpper(word) {
+		return strings.ToUpper(name)
+	}
+	return strings.ToLower(name)
+}
+
+// dedupe removes names that repeat an earlier one, ignoring case, and
+// caps them at maxSuggestions.
+func dedupe(names []string) []string {
+	var out []string
+	for _, name := range names {
+		if !slices.ContainsFunc(out, func(n string) bool { return strings.EqualFold(n, name) }) {
+			out = append(out, name)
+		}
+	}
+	return out[:min(len(out), maxSuggestions)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
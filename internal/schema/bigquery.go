@@ -0,0 +1,176 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// bqField is a field of BigQuery's native JSON table schema, as printed by
+// `bq show --schema --format=prettyjson`.
+type bqField struct {
+	Name             string    `json:"name"`
+	Type             string    `json:"type"`
+	Mode             string    `json:"mode"`
+	Description      string    `json:"description"`
+	Fields           []bqField `json:"fields"`
+	MaxLength        string    `json:"maxLength"`
+	Precision        string    `json:"precision"`
+	Scale            string    `json:"scale"`
+	RangeElementType *struct {
+		Type string `json:"type"`
+	} `json:"rangeElementType"`
+}
+
+// bqTable is a BigQuery table resource, as printed by
+// `bq show --format=prettyjson`. Only the schema, name and partitioning
+// are used.
+type bqTable struct {
+	TableReference *struct {
+		ProjectID string `json:"projectId"`
+		DatasetID string `json:"datasetId"`
+		TableID   string `json:"tableId"`
+	} `json:"tableReference"`
+	Description string `json:"description"`
+	Schema      struct {
+		Fields []bqField `json:"fields"`
+	} `json:"schema"`
+	TimePartitioning *TimePartitioning `json:"timePartitioning"`
+}
+
+// legacyTypes maps the legacy SQL type names used in BigQuery JSON schemas
+// to their GoogleSQL names.
+var legacyTypes = map[string]string{
+	"INTEGER":    "INT64",
+	"FLOAT":      "FLOAT64",
+	"BOOLEAN":    "BOOL",
+	"RECORD":     "STRUCT",
+	"DECIMAL":    "NUMERIC",
+	"BIGDECIMAL": "BIGNUMERIC",
+}
+
+// loadNative loads a file in BigQuery's native schema format: either a bare
+// array of fields or a whole table resource. A bare array has no table
+// name, so the table is named after the file, e.g. "dataset.orders.json"
+// becomes "dataset.orders".
+func loadNative(path string, data []byte) (*Table, error) {
+	var res bqTable
+	if isArray(data) {
+		if err := json.Unmarshal(data, &res.Schema.Fields); err != nil {
+			return nil, err
+		}
+	} else if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+
+	table := &Table{
+		Name:             strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Description:      res.Description,
+		TimePartitioning: res.TimePartitioning,
+	}
+	if ref := res.TableReference; ref != nil && ref.TableID != "" {
+		table.Name = strings.Join(nonEmpty(ref.ProjectID, ref.DatasetID, ref.TableID), ".")
+	}
+	columns, err := nativeColumns(res.Schema.Fields)
+	if err != nil {
+		return nil, err
+	}
+	table.Columns = columns
+	return table, nil
+}
+
+func nativeColumns(fields []bqField) ([]Column, error) {
+	columns := make([]Column, len(fields))
+	for i, f := range fields {
+		typ, err := nativeType(f, f.Name)
+		if err != nil {
+			return nil, err
+		}
+		columns[i] = Column{Name: f.Name, Type: typ, Mode: f.Mode, Description: f.Description}
+	}
+	return columns, nil
+}
+
+// nativeType returns the GoogleSQL type of a native schema field, e.g.
+// ARRAY<STRUCT<`id` INT64, `tags` ARRAY<STRING>>> for a repeated RECORD.
+// The field's own REQUIRED mode is not part of its type but is kept as the
+// column's mode; REQUIRED nested fields become NOT NULL. path names the
+// field in error messages.
+func nativeType(f bqField, path string) (string, error) {
+	typ := strings.ToUpper(strings.TrimSpace(f.Type))
+	if t, ok := legacyTypes[typ]; ok {
+		typ = t
+	}
+
+	switch typ {
+	case "":
+		return "", fmt.Errorf("field %s has no type", path)
+	case "STRUCT":
+		if len(f.Fields) == 0 {
+			return "", fmt.Errorf("field %s is a %s with no fields", path, f.Type)
+		}
+		parts := make([]string, len(f.Fields))
+		for i, sub := range f.Fields {
+			subType, err := nativeType(sub, path+"."+sub.Name)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = "`" + sub.Name + "` " + subType
+			if strings.EqualFold(sub.Mode, "REQUIRED") {
+				parts[i] += " NOT NULL"
+			}
+		}
+		typ = "STRUCT<" + strings.Join(parts, ", ") + ">"
+	case "RANGE":
+		if f.RangeElementType == nil {
+			return "", fmt.Errorf("field %s is a RANGE with no rangeElementType", path)
+		}
+		typ = "RANGE<" + strings.ToUpper(f.RangeElementType.Type) + ">"
+	case "STRING", "BYTES":
+		if f.MaxLength != "" {
+			typ += "(" + f.MaxLength + ")"
+		}
+	case "NUMERIC", "BIGNUMERIC":
+		if f.Precision != "" {
+			params := f.Precision
+			if f.Scale != "" {
+				params += ", " + f.Scale
+			}
+			typ += "(" + params + ")"
+		}
+	}
+
+	for _, p := range []string{f.MaxLength, f.Precision, f.Scale} {
+		if _, err := strconv.Atoi(p); p != "" && err != nil {
+			return "", fmt.Errorf("field %s has an invalid type parameter %q", path, p)
+		}
+	}
+
+	switch strings.ToUpper(f.Mode) {
+	case "", "NULLABLE", "REQUIRED":
+	case "REPEATED":
+		typ = "ARRAY<" + typ + ">"
+	default:
+		return "", fmt.Errorf("field %s has unknown mode %s", path, f.Mode)
+	}
+	return typ, nil
+}
+
+// isArray reports whether a JSON document is an array.
+func isArray(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '['
+}
+
+func nonEmpty(parts ...string) []string {
+	var out []string
+	for _, p := range parts {
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
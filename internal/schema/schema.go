@@ -2,59 +2,382 @@
 package schema
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
-// Schema represents a collection of table definitions.
+// Schema represents a collection of table definitions. Schema files list
+// tables either as {"tables": [{"name": ..., "columns": [...]}]} or as a
+// map from table name to definition, {"name": {"columns": [...]}}. Only
+// the list form can also hold routines.
 type Schema struct {
-	Tables []Table `json:"tables"`
+	Tables   []Table   `json:"tables"`
+	Routines []Routine `json:"routines,omitempty"`
 }
 
 // Table represents a table definition.
 type Table struct {
-	Name    string   `json:"name"` // Fully qualified: project.dataset.table
-	Columns []Column `json:"columns"`
+	Name        string   `json:"name"` // Fully qualified: project.dataset.table
+	Description string   `json:"description,omitempty"`
+	Columns     []Column `json:"columns"`
+
+	// SchemaFile, instead of Columns, names a file in BigQuery's native
+	// schema format holding the table's columns. A relative path is
+	// resolved against the directory of the file that names it.
+	SchemaFile string `json:"schemaFile,omitempty"`
+
+	// Query, instead of Columns, defines a view. Its columns are those the
+	// query produces, derived when the catalog is built.
+	Query string `json:"query,omitempty"`
+
+	// TimePartitioning partitions the table, as in a BigQuery table
+	// resource.
+	TimePartitioning *TimePartitioning `json:"timePartitioning,omitempty"`
+}
+
+// TimePartitioning describes how a table is partitioned by time. Without a
+// field the table is partitioned by ingestion time.
+type TimePartitioning struct {
+	Type  string `json:"type,omitempty"`  // DAY (the default), HOUR, MONTH or YEAR
+	Field string `json:"field,omitempty"` // partitioning column
+}
+
+// IsView reports whether t is a view defined by a query.
+func (t *Table) IsView() bool {
+	return t.Query != ""
+}
+
+// PseudoColumns returns the pseudo-columns BigQuery adds to t:
+// _PARTITIONTIME for a table partitioned by ingestion time, and
+// _PARTITIONDATE as well if it is partitioned by day.
+func (t *Table) PseudoColumns() []Column {
+	p := t.TimePartitioning
+	if p == nil || p.Field != "" {
+		return nil
+	}
+	columns := []Column{{Name: "_PARTITIONTIME", Type: "TIMESTAMP"}}
+	if p.Type == "" || strings.EqualFold(p.Type, "DAY") {
+		columns = append(columns, Column{Name: "_PARTITIONDATE", Type: "DATE"})
+	}
+	return columns
+}
+
+func (t *Table) validate() error {
+	if t.IsView() && (len(t.Columns) > 0 || t.SchemaFile != "") {
+		return fmt.Errorf("view %s has both a query and columns", t.Name)
+	}
+	for i := range t.Columns {
+		if err := t.Columns[i].validate(t.Name); err != nil {
+			return err
+		}
+	}
+	if p := t.TimePartitioning; p != nil {
+		if t.IsView() {
+			return fmt.Errorf("view %s cannot be partitioned", t.Name)
+		}
+		switch strings.ToUpper(p.Type) {
+		case "", "DAY", "HOUR", "MONTH", "YEAR":
+		default:
+			return fmt.Errorf("table %s has unknown partitioning type %s", t.Name, p.Type)
+		}
+	}
+	return nil
+}
+
+// Routine is a user-defined function, table function or procedure.
+type Routine struct {
+	Name       string     `json:"name"`               // e.g. dataset.my_udf
+	Type       string     `json:"type,omitempty"`     // SCALAR_FUNCTION (the default), TABLE_VALUED_FUNCTION or PROCEDURE
+	Language   string     `json:"language,omitempty"` // SQL (the default) or JAVASCRIPT
+	Arguments  []Argument `json:"arguments,omitempty"`
+	ReturnType string     `json:"returnType,omitempty"` // required for JAVASCRIPT
+	Body       string     `json:"body"`                 // SQL expression, query, script or JavaScript code
+
+	// ReturnTable optionally declares the columns a table function
+	// returns. Without it they are those its query produces.
+	ReturnTable []Column `json:"returnTable,omitempty"`
+}
+
+// Argument is an argument of a routine.
+type Argument struct {
+	Name string `json:"name"`
+	Type string `json:"type"`           // a BigQuery type, TABLE<...>, or ANY TYPE / ANY TABLE for a templated argument
+	Mode string `json:"mode,omitempty"` // procedures only: IN (the default), OUT or INOUT
+}
+
+// IsJavaScript reports whether r is a JavaScript UDF.
+func (r *Routine) IsJavaScript() bool {
+	return strings.EqualFold(r.Language, "JAVASCRIPT") || strings.EqualFold(r.Language, "JS")
+}
+
+// IsTableFunction reports whether r is a table-valued function, called in
+// a FROM clause.
+func (r *Routine) IsTableFunction() bool {
+	return strings.EqualFold(r.Type, "TABLE_VALUED_FUNCTION")
+}
+
+// IsProcedure reports whether r is a stored procedure, called with CALL.
+// Its body is a script, with or without the enclosing BEGIN ... END.
+func (r *Routine) IsProcedure() bool {
+	return strings.EqualFold(r.Type, "PROCEDURE")
+}
+
+func (r *Routine) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("routine has no name")
+	}
+	if r.Body == "" {
+		return fmt.Errorf("routine %s has no body", r.Name)
+	}
+	if r.Language != "" && !strings.EqualFold(r.Language, "SQL") && !r.IsJavaScript() {
+		return fmt.Errorf("routine %s has unknown language %s", r.Name, r.Language)
+	}
+	if r.Type != "" && !strings.EqualFold(r.Type, "SCALAR_FUNCTION") && !r.IsTableFunction() && !r.IsProcedure() {
+		return fmt.Errorf("routine %s has unknown type %s", r.Name, r.Type)
+	}
+	if r.IsTableFunction() && r.IsJavaScript() {
+		return fmt.Errorf("table function %s must be written in SQL", r.Name)
+	}
+	if r.IsProcedure() && (r.IsJavaScript() || r.ReturnType != "") {
+		return fmt.Errorf("procedure %s must be written in SQL and cannot have a returnType", r.Name)
+	}
+	for _, a := range r.Arguments {
+		switch strings.ToUpper(a.Mode) {
+		case "":
+		case "IN", "OUT", "INOUT":
+			if !r.IsProcedure() {
+				return fmt.Errorf("argument %s of routine %s has a mode but the routine is not a PROCEDURE", a.Name, r.Name)
+			}
+		default:
+			return fmt.Errorf("argument %s of routine %s has unknown mode %s", a.Name, r.Name, a.Mode)
+		}
+	}
+	if len(r.ReturnTable) > 0 && !r.IsTableFunction() {
+		return fmt.Errorf("routine %s has a returnTable but is not a TABLE_VALUED_FUNCTION", r.Name)
+	}
+	return nil
 }
 
 // Column represents a column definition.
 type Column struct {
-	Name string `json:"name"`
-	Type string `json:"type"` // BigQuery type: INT64, STRING, ARRAY<STRING>, etc.
+	Name        string `json:"name"`
+	Type        string `json:"type"`           // BigQuery type: INT64, STRING, ARRAY<STRING>, etc.
+	Mode        string `json:"mode,omitempty"` // NULLABLE (the default), REQUIRED or REPEATED
+	Description string `json:"description,omitempty"`
+}
+
+// validate checks c's mode. As in BigQuery's native format, a REPEATED
+// column may be declared with its element type rather than an ARRAY type.
+func (c *Column) validate(table string) error {
+	switch strings.ToUpper(c.Mode) {
+	case "", "NULLABLE", "REQUIRED", "REPEATED":
+	default:
+		return fmt.Errorf("column %s of table %s has unknown mode %s", c.Name, table, c.Mode)
+	}
+	return nil
 }
 
-// LoadFile loads a schema from a JSON file.
+// LoadFile loads a schema from a JSON file. Besides this package's own
+// format, the file may hold a BigQuery native schema, as printed by
+// `bq show --schema --format=prettyjson` (the table is named after the
+// file), or a whole table resource from `bq show --format=prettyjson` (the
+// table is named by its tableReference).
 func LoadFile(path string) (*Schema, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("reading schema file %s: %w", path, err)
 	}
-	var s Schema
-	if err := json.Unmarshal(data, &s); err != nil {
+	s, err := parse(path, data)
+	if err != nil {
 		return nil, fmt.Errorf("parsing schema file %s: %w", path, err)
 	}
+	return s, nil
+}
+
+func parse(path string, data []byte) (*Schema, error) {
+	var s *Schema
+	var err error
+	switch {
+	case isNative(data):
+		var table *Table
+		if table, err = loadNative(path, data); err == nil {
+			s = &Schema{Tables: []Table{*table}}
+		}
+	case hasMember(data, "tables") || hasMember(data, "routines"):
+		s, err = parseTableList(data)
+	default:
+		s, err = parseTableMap(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(s.Tables) == 0 && len(s.Routines) == 0 {
+		return nil, fmt.Errorf("no tables or routines defined")
+	}
+	for i := range s.Routines {
+		if err := s.Routines[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+	for i := range s.Tables {
+		t := &s.Tables[i]
+		if err := t.validate(); err != nil {
+			return nil, err
+		}
+		if err := resolveSchemaFile(t, filepath.Dir(path)); err != nil {
+			return nil, err
+		}
+		if !t.IsView() && len(t.Columns) == 0 {
+			return nil, fmt.Errorf("table %s has no columns", t.Name)
+		}
+	}
+	return s, nil
+}
+
+// parseTableList parses the {"tables": [{"name": ..., "columns": [...]}]}
+// shape, with optional "routines".
+func parseTableList(data []byte) (*Schema, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var s Schema
+	if err := dec.Decode(&s); err != nil {
+		return nil, err
+	}
 	return &s, nil
 }
 
-// LoadDir loads all .json schema files from a directory.
+// parseTableMap parses the {"project.dataset.table": {"columns": [...]}}
+// shape, keeping the tables in file order.
+func parseTableMap(data []byte) (*Schema, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if tok, err := dec.Token(); err != nil {
+		return nil, err
+	} else if tok != json.Delim('{') {
+		return nil, fmt.Errorf("expected a JSON object or array")
+	}
+
+	s := &Schema{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		name := tok.(string)
+		var t Table
+		if err := dec.Decode(&t); err != nil {
+			return nil, fmt.Errorf("table %s: %w", name, err)
+		}
+		if t.Name != "" {
+			return nil, fmt.Errorf("table %s: unexpected name %q, the key names the table", name, t.Name)
+		}
+		t.Name = name
+		s.Tables = append(s.Tables, t)
+	}
+	return s, nil
+}
+
+// hasMember reports whether a JSON document is an object with the given
+// member.
+func hasMember(data []byte, name string) bool {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return false
+	}
+	_, ok := members[name]
+	return ok
+}
+
+// isNative reports whether a schema file is in BigQuery's native format: a
+// bare array of fields, or a table resource, whose "schema" member holds
+// the fields. A table map may name a table "schema", but its definition
+// has no "fields".
+func isNative(data []byte) bool {
+	if isArray(data) {
+		return true
+	}
+	var resource struct {
+		Schema struct {
+			Fields json.RawMessage `json:"fields"`
+		} `json:"schema"`
+	}
+	return json.Unmarshal(data, &resource) == nil && resource.Schema.Fields != nil
+}
+
+// resolveSchemaFile loads the columns of a table that names a native
+// schema file.
+func resolveSchemaFile(t *Table, dir string) error {
+	if t.SchemaFile == "" {
+		return nil
+	}
+	if len(t.Columns) > 0 {
+		return fmt.Errorf("table %s has both columns and a schemaFile", t.Name)
+	}
+	path := t.SchemaFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("table %s: %w", t.Name, err)
+	}
+	native, err := loadNative(path, data)
+	if err != nil {
+		return fmt.Errorf("table %s: parsing %s: %w", t.Name, path, err)
+	}
+	if t.Name == "" {
+		t.Name = native.Name
+	}
+	if t.Description == "" {
+		t.Description = native.Description
+	}
+	if t.TimePartitioning == nil {
+		t.TimePartitioning = native.TimePartitioning
+	}
+	t.Columns = native.Columns
+	return nil
+}
+
+// LoadDir loads all .json schema files from a directory. Native schema
+// files that a manifest in the directory names with schemaFile are loaded
+// only through the manifest.
 func LoadDir(dir string) (*Schema, error) {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return nil, fmt.Errorf("reading schema directory %s: %w", dir, err)
 	}
 
-	merged := &Schema{}
+	var paths []string
+	loaded := make(map[string]*Schema)
+	referenced := make(map[string]bool)
 	for _, entry := range entries {
 		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
 			continue
 		}
-		s, err := LoadFile(filepath.Join(dir, entry.Name()))
+		path := filepath.Join(dir, entry.Name())
+		s, err := LoadFile(path)
 		if err != nil {
 			return nil, err
 		}
-		merged.Tables = append(merged.Tables, s.Tables...)
+		paths = append(paths, path)
+		loaded[path] = s
+		for _, t := range s.Tables {
+			if t.SchemaFile != "" && !filepath.IsAbs(t.SchemaFile) {
+				referenced[filepath.Join(dir, t.SchemaFile)] = true
+			}
+		}
+	}
+
+	merged := &Schema{}
+	for _, path := range paths {
+		if !referenced[path] {
+			merged.Tables = append(merged.Tables, loaded[path].Tables...)
+			merged.Routines = append(merged.Routines, loaded[path].Routines...)
+		}
 	}
 	return merged, nil
 }
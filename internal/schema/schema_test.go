@@ -83,3 +83,337 @@ func TestLoadFileInvalidJSON(t *testing.T) {
 		t.Error("expected error for invalid JSON")
 	}
 }
+
+func TestLoadFileNative(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sales.orders.json")
+	err := os.WriteFile(path, []byte(`[
+		{"name": "id", "type": "INTEGER", "mode": "REQUIRED", "description": "Order ID"},
+		{"name": "total", "type": "NUMERIC", "precision": "10", "scale": "2"},
+		{"name": "code", "type": "STRING", "maxLength": "8"},
+		{"name": "paid", "type": "BOOLEAN", "mode": "NULLABLE"},
+		{"name": "weight", "type": "FLOAT"},
+		{"name": "tags", "type": "STRING", "mode": "REPEATED"},
+		{"name": "items", "type": "RECORD", "mode": "REPEATED", "fields": [
+			{"name": "sku", "type": "STRING", "mode": "REQUIRED"},
+			{"name": "options", "type": "STRUCT", "fields": [
+				{"name": "gift", "type": "BOOL"}
+			]}
+		]},
+		{"name": "valid", "type": "RANGE", "rangeElementType": {"type": "DATE"}}
+	]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if len(s.Tables) != 1 || s.Tables[0].Name != "sales.orders" {
+		t.Fatalf("tables = %+v, want one table named sales.orders", s.Tables)
+	}
+
+	want := []Column{
+		{Name: "id", Type: "INT64", Mode: "REQUIRED", Description: "Order ID"},
+		{Name: "total", Type: "NUMERIC(10, 2)"},
+		{Name: "code", Type: "STRING(8)"},
+		{Name: "paid", Type: "BOOL", Mode: "NULLABLE"},
+		{Name: "weight", Type: "FLOAT64"},
+		{Name: "tags", Type: "ARRAY<STRING>", Mode: "REPEATED"},
+		{Name: "items", Type: "ARRAY<STRUCT<`sku` STRING NOT NULL, `options` STRUCT<`gift` BOOL>>>", Mode: "REPEATED"},
+		{Name: "valid", Type: "RANGE<DATE>"},
+	}
+	got := s.Tables[0].Columns
+	if len(got) != len(want) {
+		t.Fatalf("columns = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("column %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLoadFileTableResource(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "orders.json")
+	err := os.WriteFile(path, []byte(`{
+		"kind": "bigquery#table",
+		"tableReference": {"projectId": "my-project", "datasetId": "sales", "tableId": "orders"},
+		"schema": {"fields": [{"name": "id", "type": "INTEGER"}]},
+		"timePartitioning": {"type": "DAY"}
+	}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if len(s.Tables) != 1 || s.Tables[0].Name != "my-project.sales.orders" {
+		t.Fatalf("tables = %+v, want one table named my-project.sales.orders", s.Tables)
+	}
+	if got := s.Tables[0].PseudoColumns(); len(got) != 2 || got[0].Name != "_PARTITIONTIME" || got[1].Name != "_PARTITIONDATE" {
+		t.Errorf("PseudoColumns() = %+v, want _PARTITIONTIME and _PARTITIONDATE", got)
+	}
+}
+
+func TestPseudoColumns(t *testing.T) {
+	tests := []struct {
+		partitioning *TimePartitioning
+		want         int
+	}{
+		{nil, 0},
+		{&TimePartitioning{Type: "DAY", Field: "created_at"}, 0},
+		{&TimePartitioning{}, 2},
+		{&TimePartitioning{Type: "HOUR"}, 1},
+	}
+	for _, tt := range tests {
+		table := Table{Name: "t", TimePartitioning: tt.partitioning}
+		if got := table.PseudoColumns(); len(got) != tt.want {
+			t.Errorf("PseudoColumns() with %+v = %+v, want %d columns", tt.partitioning, got, tt.want)
+		}
+	}
+}
+
+func TestLoadFileNativeErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"no type":      `[{"name": "id"}]`,
+		"empty record": `[{"name": "r", "type": "RECORD"}]`,
+		"bad mode":     `[{"name": "id", "type": "INT64", "mode": "OPTIONAL"}]`,
+		"nested":       `[{"name": "r", "type": "RECORD", "fields": [{"name": "x"}]}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "t.json")
+			if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadFile(path); err == nil {
+				t.Errorf("LoadFile(%s) succeeded", data)
+			}
+		})
+	}
+}
+
+func TestLoadDirManifest(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"manifest.json": `{"tables": [
+			{"name": "project.sales.orders", "schemaFile": "orders.schema.json"},
+			{"name": "project.sales.refunds", "columns": [{"name": "id", "type": "INT64"}]}
+		]}`,
+		"orders.schema.json": `[{"name": "id", "type": "INTEGER"}, {"name": "note", "type": "STRING"}]`,
+		"customers.json":     `[{"name": "id", "type": "INTEGER"}]`,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir: %v", err)
+	}
+	names := make(map[string]int)
+	for _, table := range s.Tables {
+		names[table.Name] = len(table.Columns)
+	}
+	want := map[string]int{"project.sales.orders": 2, "project.sales.refunds": 1, "customers": 1}
+	if len(names) != len(want) || len(s.Tables) != len(want) {
+		t.Fatalf("tables = %v, want %v", names, want)
+	}
+	for name, cols := range want {
+		if names[name] != cols {
+			t.Errorf("table %s has %d columns, want %d", name, names[name], cols)
+		}
+	}
+}
+
+func TestLoadFileTableMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	err := os.WriteFile(path, []byte(`{
+		"project.dataset.users": {
+			"columns": [
+				{"name": "id", "type": "INT64"},
+				{"name": "email", "type": "STRING"}
+			]
+		},
+		"project.dataset.accounts": {
+			"description": "Billing accounts",
+			"columns": [{"name": "id", "type": "INT64"}]
+		}
+	}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if len(s.Tables) != 2 {
+		t.Fatalf("expected 2 tables, got %d", len(s.Tables))
+	}
+	// Tables keep their order in the file.
+	if s.Tables[0].Name != "project.dataset.users" || s.Tables[1].Name != "project.dataset.accounts" {
+		t.Errorf("table names = %q, %q", s.Tables[0].Name, s.Tables[1].Name)
+	}
+	if len(s.Tables[0].Columns) != 2 || s.Tables[1].Description != "Billing accounts" {
+		t.Errorf("tables = %+v", s.Tables)
+	}
+}
+
+func TestLoadFileTableMapNamedSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	err := os.WriteFile(path, []byte(`{
+		"schema": {"columns": [{"name": "version", "type": "INT64"}]},
+		"users": {"columns": [{"name": "id", "type": "INT64"}]}
+	}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if len(s.Tables) != 2 || s.Tables[0].Name != "schema" || s.Tables[1].Name != "users" {
+		t.Errorf("tables = %+v, want schema and users", s.Tables)
+	}
+}
+
+func TestLoadFileColumnModes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	err := os.WriteFile(path, []byte(`{"tables": [{"name": "t", "columns": [
+		{"name": "id", "type": "INT64", "mode": "REQUIRED"},
+		{"name": "note", "type": "STRING", "mode": "NULLABLE"},
+		{"name": "tags", "type": "STRING", "mode": "REPEATED"},
+		{"name": "scores", "type": "ARRAY<INT64>", "mode": "REPEATED"}
+	]}]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	want := []Column{
+		{Name: "id", Type: "INT64", Mode: "REQUIRED"},
+		{Name: "note", Type: "STRING", Mode: "NULLABLE"},
+		{Name: "tags", Type: "STRING", Mode: "REPEATED"},
+		{Name: "scores", Type: "ARRAY<INT64>", Mode: "REPEATED"},
+	}
+	got := s.Tables[0].Columns
+	if len(got) != len(want) {
+		t.Fatalf("columns = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("column %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLoadFileRejects(t *testing.T) {
+	for name, data := range map[string]string{
+		"empty object":        `{}`,
+		"empty tables":        `{"tables": []}`,
+		"unknown table key":   `{"tables": [{"name": "t", "colums": [{"name": "a", "type": "INT64"}]}]}`,
+		"unknown column key":  `{"tables": [{"name": "t", "columns": [{"name": "a", "typ": "INT64"}]}]}`,
+		"unknown map key":     `{"t": {"cols": [{"name": "a", "type": "INT64"}]}}`,
+		"map with name":       `{"t": {"name": "u", "columns": [{"name": "a", "type": "INT64"}]}}`,
+		"map of non-objects":  `{"t": [1, 2]}`,
+		"partitioning type":   `{"t": {"columns": [{"name": "a", "type": "INT64"}], "timePartitioning": {"type": "WEEK"}}}`,
+		"partitioned view":    `{"t": {"query": "SELECT 1", "timePartitioning": {"type": "DAY"}}}`,
+		"column mode":         `{"t": {"columns": [{"name": "a", "type": "INT64", "mode": "OPTIONAL"}]}}`,
+		"no columns":          `{"tables": [{"name": "t", "columns": []}]}`,
+		"map without columns": `{"t": {"description": "x"}}`,
+		"native no fields":    `[]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "schema.json")
+			if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadFile(path); err == nil {
+				t.Errorf("LoadFile(%s) succeeded", data)
+			}
+		})
+	}
+}
+
+func TestLoadFileViews(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	err := os.WriteFile(path, []byte(`{
+		"dataset.orders": {"columns": [{"name": "id", "type": "INT64"}]},
+		"dataset.recent_orders": {"query": "SELECT id FROM dataset.orders"}
+	}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if len(s.Tables) != 2 || s.Tables[0].IsView() || !s.Tables[1].IsView() {
+		t.Errorf("tables = %+v, want a table and a view", s.Tables)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.json")
+	err = os.WriteFile(bad, []byte(`{"v": {"query": "SELECT 1 AS x", "columns": [{"name": "x", "type": "INT64"}]}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(bad); err == nil {
+		t.Error("LoadFile accepted a view with both a query and columns")
+	}
+}
+
+func TestLoadFileRoutines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routines.json")
+	err := os.WriteFile(path, []byte(`{"routines": [
+		{"name": "dataset.add_one", "arguments": [{"name": "x", "type": "INT64"}], "body": "x + 1"},
+		{"name": "dataset.js_len", "language": "JAVASCRIPT", "arguments": [{"name": "s", "type": "STRING"}],
+		 "returnType": "INT64", "body": "return s.length;"},
+		{"name": "dataset.recent", "type": "TABLE_VALUED_FUNCTION", "arguments": [{"name": "d", "type": "DATE"}],
+		 "returnTable": [{"name": "id", "type": "INT64"}], "body": "SELECT id FROM dataset.events WHERE day >= d"},
+		{"name": "dataset.count_events", "type": "PROCEDURE", "arguments": [{"name": "n", "type": "INT64", "mode": "OUT"}],
+		 "body": "SET n = (SELECT COUNT(*) FROM dataset.events);"}
+	]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if len(s.Routines) != 4 || s.Routines[0].IsJavaScript() || !s.Routines[1].IsJavaScript() ||
+		!s.Routines[2].IsTableFunction() || !s.Routines[3].IsProcedure() {
+		t.Errorf("routines = %+v", s.Routines)
+	}
+
+	for name, data := range map[string]string{
+		"no body":                `{"routines": [{"name": "f"}]}`,
+		"unknown language":       `{"routines": [{"name": "f", "language": "PYTHON", "body": "1"}]}`,
+		"JavaScript TVF":         `{"routines": [{"name": "f", "type": "TABLE_VALUED_FUNCTION", "language": "JS", "body": "1"}]}`,
+		"scalar returnTable":     `{"routines": [{"name": "f", "returnTable": [{"name": "x", "type": "INT64"}], "body": "1"}]}`,
+		"function argument mode": `{"routines": [{"name": "f", "arguments": [{"name": "x", "type": "INT64", "mode": "OUT"}], "body": "1"}]}`,
+		"unknown argument mode":  `{"routines": [{"name": "p", "type": "PROCEDURE", "arguments": [{"name": "x", "type": "INT64", "mode": "BOTH"}], "body": "SELECT 1;"}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			bad := filepath.Join(t.TempDir(), "bad.json")
+			if err := os.WriteFile(bad, []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadFile(bad); err == nil {
+				t.Errorf("LoadFile(%s) succeeded", data)
+			}
+		})
+	}
+}